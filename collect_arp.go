@@ -0,0 +1,68 @@
+package main
+
+// Metric families reported from "show ip arp detail vrf all"
+var (
+	famIpArpEntries = &metricFamily{Name: "cisco_ip_arp_entries", Type: "gauge",
+		Help: "Number of ARP entries in the VRF.", Labels: []string{"vrf"}}
+	famIpArpIntfEntries = &metricFamily{Name: "cisco_ip_arp_interface_entries", Type: "gauge",
+		Help: "Number of ARP entries on the interface.", Labels: []string{"vrf", "interface"}}
+
+	// Only with arp_entries, a series for every ARP entry
+	famIpArp = &metricFamily{Name: "cisco_ip_arp", Type: "gauge",
+		Help:   "Age of the ARP entry in seconds.",
+		Labels: []string{"vrf", "intfOut", "iPAddrOut", "mac", "phyIntf"}}
+)
+
+// Reply to "show ip arp detail vrf all".  The client reads the age of an
+// entry as a time of day, while the switch reports how long ago the entry was
+// refreshed, like "00:12:51".
+type arpResult struct {
+	Body struct {
+		TableVrf []struct {
+			RowVrf []struct {
+				VrfNameOut string `json:"vrf-name-out"`
+				CntTotal   int    `json:"cnt-total"`
+				TableAdj   []struct {
+					RowAdj []struct {
+						IntfOut   string   `json:"intf-out"`
+						IPAddrOut string   `json:"ip-addr-out"`
+						TimeStamp duration `json:"time-stamp"`
+						Mac       string   `json:"mac"`
+						PhyIntf   string   `json:"phy-intf"`
+					} `json:"ROW_adj"`
+				} `json:"TABLE_adj"`
+			} `json:"ROW_vrf"`
+		} `json:"TABLE_vrf"`
+	} `json:"body"`
+}
+
+func newArpResult(b []byte) (*arpResult, error) {
+	arp := &arpResult{}
+	if err := decodeResult(b, arp); err != nil {
+		return nil, err
+	}
+	return arp, nil
+}
+
+// ARP entries of every VRF from "show ip arp detail vrf all", counted per VRF
+// and interface.  The entries themselves are only exported when entries is set.
+func collectArp(ms *metricSet, arp *arpResult, entries bool) {
+	for _, t := range arp.Body.TableVrf {
+		for _, v := range t.RowVrf {
+			ms.Add(famIpArpEntries, float64(v.CntTotal), v.VrfNameOut)
+
+			var intfs rowCount
+			for _, ta := range v.TableAdj {
+				for _, r := range ta.RowAdj {
+					intfs.Add(r.IntfOut)
+
+					if entries {
+						ms.Add(famIpArp, float64(r.TimeStamp/1e9),
+							v.VrfNameOut, r.IntfOut, r.IPAddrOut, r.Mac, r.PhyIntf)
+					}
+				}
+			}
+			intfs.AddTo(ms, famIpArpIntfEntries, v.VrfNameOut)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestArpEmptyTimeStamp(t *testing.T) {
+	arp, err := newArpResult([]byte(`{"body": {"TABLE_vrf": {"ROW_vrf": {"vrf-name-out": "default", "cnt-total": 2,
+		"TABLE_adj": {"ROW_adj": [
+			{"intf-out": "Vlan10", "ip-addr-out": "10.0.10.5", "time-stamp": "00:04:12", "mac": "0011.2233.4455", "phy-intf": "Ethernet1/5"},
+			{"intf-out": "Vlan10", "ip-addr-out": "10.0.10.6", "time-stamp": "", "mac": "0011.2233.4466", "phy-intf": "Ethernet1/6"}]}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := arp.Body.TableVrf[0].RowVrf[0].TableAdj[0].RowAdj
+	if len(rows) != 2 {
+		t.Fatalf("got %d entries, want 2", len(rows))
+	}
+	if rows[0].TimeStamp != duration(252e9) || rows[1].TimeStamp != 0 {
+		t.Errorf("time stamps %d and %d, want 252s and 0", rows[0].TimeStamp, rows[1].TimeStamp)
+	}
+}
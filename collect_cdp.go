@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show cdp neighbors"
+var (
+	famCdpNeighbor = &metricFamily{Name: "cisco_cdp_neighbor_info", Type: "info",
+		Help:   "Neighbor seen over CDP on the interface.",
+		Labels: []string{"interface", "remote_device", "remote_port", "platform", "capabilities"}}
+	famCdpCount = &metricFamily{Name: "cisco_cdp_neighbor_count", Type: "gauge",
+		Help: "Number of neighbors seen over CDP."}
+)
+
+// Neighbors of the switch from "show cdp neighbors"
+func collectCdp(ms *metricSet, cdp *client.ShowCdpNeighborsResponseResult) {
+	count := 0
+	for _, t := range cdp.Body.TableCdpNeighborBriefInfo {
+		for _, r := range t.RowCdpNeighborBriefInfo {
+			ms.Add(famCdpNeighbor, 1, longInterfaceName(r.IntfID), r.DeviceID, r.PortID, r.PlatformID,
+				strings.Join(r.Capability, ","))
+			count++
+		}
+	}
+	// Older releases leave out the count
+	if cdp.Body.NeighCount > 0 {
+		count = cdp.Body.NeighCount
+	}
+	ms.Add(famCdpCount, float64(count))
+}
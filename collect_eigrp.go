@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show ip eigrp neighbors vrf all"
+var (
+	eigrpLabels      = []string{"asn", "vrf", "peer", "interface"}
+	famEigrpHoldtime = &metricFamily{Name: "cisco_eigrp_neighbor_holdtime_seconds", Type: "gauge",
+		Help: "Seconds left before the EIGRP neighbor is declared down.", Labels: eigrpLabels}
+	famEigrpSrtt = &metricFamily{Name: "cisco_eigrp_neighbor_srtt_seconds", Type: "gauge",
+		Help: "Smooth round trip time to the EIGRP neighbor in seconds.", Labels: eigrpLabels}
+	famEigrpRto = &metricFamily{Name: "cisco_eigrp_neighbor_rto_seconds", Type: "gauge",
+		Help: "Retransmission timeout of the EIGRP neighbor in seconds.", Labels: eigrpLabels}
+	famEigrpQueue = &metricFamily{Name: "cisco_eigrp_neighbor_queue_count", Type: "gauge",
+		Help: "Packets queued to be sent to the EIGRP neighbor.", Labels: eigrpLabels}
+	famEigrpSeqno = &metricFamily{Name: "cisco_eigrp_neighbor_last_seqno", Type: "gauge",
+		Help: "Sequence number of the last packet received from the EIGRP neighbor.", Labels: eigrpLabels}
+	famEigrpUptime = &metricFamily{Name: "cisco_eigrp_neighbor_uptime_seconds", Type: "gauge",
+		Help: "Seconds since the EIGRP neighbor came up.", Labels: eigrpLabels}
+)
+
+// A neighbor with the autonomous system and VRF it is in
+type eigrpResultFlat struct {
+	Asn            string
+	Vrf            string
+	PeerIpaddr     string
+	PeerIfname     string
+	PeerHoldtime   uint
+	PeerSrtt       uint
+	PeerRto        uint
+	PeerXmitqCount uint
+	PeerLastSeqno  uint
+	PeerUptime     client.Duration
+}
+
+// Flatten the reply of "show ip eigrp neighbors vrf all" to a row per
+// neighbor, the way Flat does for the other results of the client
+func flatEigrp(d *client.ShowIpEigrpNeighborsVrfAllResponseResult) (out []eigrpResultFlat) {
+	for _, Ta := range d.Body.TableAsn {
+		for _, Ra := range Ta.RowAsn {
+			for _, Tv := range Ra.TableVrf {
+				for _, Rv := range Tv.RowVrf {
+					for _, Tp := range Rv.TablePeer {
+						for _, Rp := range Tp.RowPeer {
+							out = append(out, eigrpResultFlat{
+								Asn:            Ra.Asn,
+								Vrf:            Rv.Vrf,
+								PeerIpaddr:     Rp.PeerIpaddr,
+								PeerIfname:     Rp.PeerIfname,
+								PeerHoldtime:   Rp.PeerHoldtime,
+								PeerSrtt:       Rp.PeerSrtt,
+								PeerRto:        Rp.PeerRto,
+								PeerXmitqCount: Rp.PeerXmitqCount,
+								PeerLastSeqno:  Rp.PeerLastSeqno,
+								PeerUptime:     Rp.PeerUptime,
+							})
+						}
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// EIGRP neighbors of every autonomous system and VRF from
+// "show ip eigrp neighbors vrf all"
+func collectEigrp(ms *metricSet, eigrp *client.ShowIpEigrpNeighborsVrfAllResponseResult) {
+	for _, r := range flatEigrp(eigrp) {
+		lbl := []string{r.Asn, r.Vrf, r.PeerIpaddr, longInterfaceName(r.PeerIfname)}
+		ms.Add(famEigrpHoldtime, float64(r.PeerHoldtime), lbl...)
+		// The switch reports the timers in milliseconds
+		ms.Add(famEigrpSrtt, fromMilli(float64(r.PeerSrtt)), lbl...)
+		ms.Add(famEigrpRto, fromMilli(float64(r.PeerRto)), lbl...)
+		ms.Add(famEigrpQueue, float64(r.PeerXmitqCount), lbl...)
+		ms.Add(famEigrpSeqno, float64(r.PeerLastSeqno), lbl...)
+		ms.Add(famEigrpUptime, float64(r.PeerUptime/1e9), lbl...)
+	}
+}
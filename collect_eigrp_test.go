@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+func TestFlatEigrp(t *testing.T) {
+	dat, err := ioutil.ReadFile("testdata/show_ip_eigrp_neighbors_vrf_all.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eigrp, err := client.NewShowIpEigrpNeighborsVrfAllResultFromBytes(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := flatEigrp(eigrp)
+
+	want := []eigrpResultFlat{
+		{"100", "default", "10.1.1.2", "Eth1/1", 13, 2, 50, 0, 1245, 93784e9},
+		{"100", "default", "10.1.2.2", "Eth1/2", 12, 1520, 5000, 7, 1301, 9e9},
+		{"100", "blue", "10.2.1.2", "Vlan20", 11, 1, 50, 3, 88, 754e9},
+		{"200", "red", "10.3.1.2", "Po10", 14, 4, 50, 0, 17, 259200e9},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d:\n%+v\nwant:\n%+v", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestCollectEigrp(t *testing.T) {
+	dat, err := ioutil.ReadFile("testdata/show_ip_eigrp_neighbors_vrf_all.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eigrp, err := client.NewShowIpEigrpNeighborsVrfAllResultFromBytes(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := newMetricSet()
+	collectEigrp(ms, eigrp)
+
+	got := string(ms.Format(formatText))
+	for _, want := range []string{
+		`cisco_eigrp_neighbor_holdtime_seconds{asn="100",vrf="default",peer="10.1.1.2",interface="Ethernet1/1"} 13`,
+		`cisco_eigrp_neighbor_srtt_seconds{asn="100",vrf="default",peer="10.1.2.2",interface="Ethernet1/2"} 1.52`,
+		`cisco_eigrp_neighbor_rto_seconds{asn="100",vrf="default",peer="10.1.2.2",interface="Ethernet1/2"} 5`,
+		`cisco_eigrp_neighbor_queue_count{asn="100",vrf="blue",peer="10.2.1.2",interface="Vlan20"} 3`,
+		`cisco_eigrp_neighbor_last_seqno{asn="100",vrf="blue",peer="10.2.1.2",interface="Vlan20"} 88`,
+		`cisco_eigrp_neighbor_uptime_seconds{asn="200",vrf="red",peer="10.3.1.2",interface="port-channel10"} 259200`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
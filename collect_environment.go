@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show environment"
+var (
+	tempLabels = []string{"module", "sensor"}
+	famTemp    = &metricFamily{Name: "cisco_temperature_celsius", Type: "gauge",
+		Help: "Current temperature of the sensor in degrees celsius.", Labels: tempLabels}
+	famTempMajor = &metricFamily{Name: "cisco_temperature_major_threshold_celsius", Type: "gauge",
+		Help: "Major alarm threshold of the sensor in degrees celsius.", Labels: tempLabels}
+	famTempMinor = &metricFamily{Name: "cisco_temperature_minor_threshold_celsius", Type: "gauge",
+		Help: "Minor alarm threshold of the sensor in degrees celsius.", Labels: tempLabels}
+	famTempAlarm = &metricFamily{Name: "cisco_temperature_alarm", Type: "gauge",
+		Help: "Alarm status of the sensor, 1 when the sensor is not ok.", Labels: tempLabels}
+
+	famFanStatus = &metricFamily{Name: "cisco_fan_status", Type: "stateset",
+		Help:   "Status of the fan, 1 when ok.",
+		Labels: []string{"fan", "model", "direction"},
+		States: []string{"ok", "absent", "failure", "none"}}
+	famFanZoneSpeed = &metricFamily{Name: "cisco_fan_zone_speed_percent", Type: "gauge",
+		Help: "Speed of the fans in the zone in percent.", Labels: []string{"zone"}}
+
+	psuLabels   = []string{"psu", "model"}
+	famPsuInput = &metricFamily{Name: "cisco_psu_input_watts", Type: "gauge",
+		Help: "Actual input power drawn by the power supply in watts.", Labels: psuLabels}
+	famPsuOutput = &metricFamily{Name: "cisco_psu_output_watts", Type: "gauge",
+		Help: "Actual output power of the power supply in watts.", Labels: psuLabels}
+	famPsuCapacity = &metricFamily{Name: "cisco_psu_capacity_watts", Type: "gauge",
+		Help: "Total capacity of the power supply in watts.", Labels: psuLabels}
+	famPsuStatus = &metricFamily{Name: "cisco_psu_status", Type: "stateset",
+		Help: "Status of the power supply, 1 when ok.", Labels: psuLabels,
+		States: []string{"ok", "absent", "shutdown", "fail/shutdown", "powered-dn"}}
+
+	famPowerRedundancy = &metricFamily{Name: "cisco_power_redundancy_info", Type: "info",
+		Help:   "Configured and operational redundancy mode of the power supplies.",
+		Labels: []string{"configured", "operational"}}
+	famPowerCapacity = &metricFamily{Name: "cisco_power_capacity_watts", Type: "gauge",
+		Help: "Total power capacity of the power supplies in watts."}
+	famPowerAvailable = &metricFamily{Name: "cisco_power_available_watts", Type: "gauge",
+		Help: "Power still available to the device in watts."}
+	famPowerInput = &metricFamily{Name: "cisco_power_input_watts", Type: "gauge",
+		Help: "Total actual input power drawn in watts."}
+	famPowerOutput = &metricFamily{Name: "cisco_power_output_watts", Type: "gauge",
+		Help: "Total actual output power in watts."}
+)
+
+// Temperatures, fans and power supplies from "show environment"
+func collectEnvironment(ms *metricSet, env *client.ShowEnvironmentResponseResult) {
+	for _, t := range env.Body.TableTempinfo {
+		for _, r := range t.RowTempinfo {
+			module := fmt.Sprint(r.Tempmod)
+			ms.Add(famTemp, float32Value(r.CurTemp), module, r.Sensor)
+			ms.Add(famTempMajor, float32Value(r.MajThres), module, r.Sensor)
+			ms.Add(famTempMinor, float32Value(r.MinThres), module, r.Sensor)
+			alarm := 0
+			if !strings.EqualFold(r.AlarmStatus, "ok") {
+				alarm = 1
+			}
+			ms.Add(famTempAlarm, float64(alarm), module, r.Sensor)
+		}
+	}
+
+	fans := &env.Body.FanDetails
+	for _, t := range fans.TableFaninfo {
+		for _, r := range t.RowFaninfo {
+			ms.AddState(famFanStatus, okState(r.FanStatus), strings.ToLower(r.FanStatus),
+				r.FanName, r.FanModel, r.FanDir)
+		}
+	}
+	for _, t := range fans.TableFanZoneSpeed {
+		for _, r := range t.RowFanZoneSpeed {
+			speed, ok := parseZoneSpeed(r.ZoneSpeed)
+			if !ok {
+				if _, seen := skippedZoneSpeeds.LoadOrStore(r.ZoneSpeed, true); !seen {
+					log.Printf("Skipping fan zone speed %q, which is neither a percentage nor hex", r.ZoneSpeed)
+				}
+				continue
+			}
+			ms.Add(famFanZoneSpeed, speed, fmt.Sprint(r.Zone))
+		}
+	}
+
+	power := &env.Body.Powersup
+	for _, t := range power.TablePsinfo {
+		for _, r := range t.RowPsinfo {
+			psu := fmt.Sprint(r.PsNum)
+			addWatts(ms, famPsuInput, r.ActualInput, psu, r.PsModel)
+			addWatts(ms, famPsuOutput, r.ActualOut, psu, r.PsModel)
+			addWatts(ms, famPsuCapacity, r.TotCapa, psu, r.PsModel)
+			ms.AddState(famPsuStatus, okState(r.PsStatus), strings.ToLower(r.PsStatus), psu, r.PsModel)
+		}
+	}
+
+	sum := &power.PowerSummary
+	if sum.PsRedunMode != "" || sum.PsOperMode != "" {
+		ms.Add(famPowerRedundancy, 1, sum.PsRedunMode, sum.PsOperMode)
+	}
+	addWatts(ms, famPowerCapacity, sum.TotPowCapacity)
+	addWatts(ms, famPowerAvailable, sum.AvailablePow)
+	addWatts(ms, famPowerInput, sum.TotPowInputActualDraw)
+	addWatts(ms, famPowerOutput, sum.TotPowOutActualDraw)
+}
+
+// Add a power reading, leaving out the "N/A" ones rather than exporting NaN
+func addWatts(ms *metricSet, f *metricFamily, w client.Watts, labelValues ...string) {
+	if math.IsNaN(float64(w)) {
+		return
+	}
+	ms.Add(f, float32Value(float32(w)), labelValues...)
+}
+
+// Status value for the text format, 1 when the status is ok
+func okState(s string) float64 {
+	return isState(s, "ok")
+}
+
+// Zone speeds are a percentage like "95%", or on several platforms the duty
+// cycle of the fan controller in hex, like "0x80", from 0x00 for stopped to
+// 0xff for full speed, which is scaled to a percentage
+func parseZoneSpeed(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		v, err := strconv.ParseUint(s[2:], 16, 8)
+		return math.Round(float64(v)*10000/255) / 100, err == nil
+	}
+	if !strings.HasSuffix(s, "%") {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	return v, err == nil
+}
+
+// Zone speeds which could not be read, so each is logged once
+var skippedZoneSpeeds sync.Map
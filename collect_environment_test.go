@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseZoneSpeed(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"95%", 95, true},
+		{" 40% ", 40, true},
+		{"0x80", 50.2, true},
+		{"0xff", 100, true},
+		{"0x00", 0, true},
+		{"0X4C", 29.8, true},
+		{"0x100", 0, false},
+		{"0xzz", 0, false},
+		{"fast", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseZoneSpeed(tt.in)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseZoneSpeed(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show interface counters errors", labeled
+// with the interface name cisco_interface_info uses so they can be joined
+var (
+	errLabels    = []string{"interface"}
+	famIntfAlign = &metricFamily{Name: "cisco_interface_align_errors", Type: "counter",
+		Help: "Frames received on the interface with an alignment error.", Labels: errLabels}
+	famIntfFcs = &metricFamily{Name: "cisco_interface_fcs_errors", Type: "counter",
+		Help: "Frames received on the interface with a bad frame check sequence (CRC).", Labels: errLabels}
+	famIntfXmitErr = &metricFamily{Name: "cisco_interface_xmit_errors", Type: "counter",
+		Help: "Frames the interface failed to transmit.", Labels: errLabels}
+	famIntfRcvErr = &metricFamily{Name: "cisco_interface_rcv_errors", Type: "counter",
+		Help: "Frames received on the interface with an error.", Labels: errLabels}
+	famIntfUndersize = &metricFamily{Name: "cisco_interface_undersize_frames", Type: "counter",
+		Help: "Frames received on the interface shorter than the minimum size.", Labels: errLabels}
+	famIntfOutDisc = &metricFamily{Name: "cisco_interface_out_discards", Type: "counter",
+		Help: "Frames discarded by the interface on the way out.", Labels: errLabels}
+)
+
+// Interface error counters from "show interface counters errors"
+func collectInterfaceErrors(ms *metricSet, errs *client.ShowInterfaceCountersErrorsResponseResult) {
+	for _, t := range errs.Body.TableInterface {
+		for _, r := range t.RowInterface {
+			intf := longInterfaceName(r.Interface)
+			ms.Add(famIntfAlign, float64(r.EthAlignErr), intf)
+			ms.Add(famIntfFcs, float64(r.EthFcsErr), intf)
+			ms.Add(famIntfXmitErr, float64(r.EthXmitErr), intf)
+			ms.Add(famIntfRcvErr, float64(r.EthRcvErr), intf)
+			ms.Add(famIntfUndersize, float64(r.EthUndersize), intf)
+			ms.Add(famIntfOutDisc, float64(r.EthOutdisc), intf)
+		}
+	}
+}
+
+// Some releases abbreviate the interface names in the counter tables, like
+// "Eth1/1" for "Ethernet1/1", while "show interface" always spells them out
+var interfacePrefixes = map[string]string{"Eth": "Ethernet", "Po": "port-channel", "Lo": "loopback"}
+
+func longInterfaceName(name string) string {
+	i := strings.IndexFunc(name, unicode.IsDigit)
+	if i <= 0 {
+		return name
+	}
+	if long, ok := interfacePrefixes[name[:i]]; ok {
+		return long + name[i:]
+	}
+	return name
+}
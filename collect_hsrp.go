@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show hsrp"
+var (
+	hsrpLabels   = []string{"interface", "group", "vip"}
+	famHsrpState = &metricFamily{Name: "cisco_hsrp_state", Type: "stateset",
+		Help: "State of the HSRP group, 1 when active.", Labels: hsrpLabels,
+		States: []string{"Active", "Standby", "Speak", "Listen", "Learn", "Init"}}
+	famHsrpPriority = &metricFamily{Name: "cisco_hsrp_priority", Type: "gauge",
+		Help: "Current priority of the switch in the HSRP group.", Labels: hsrpLabels}
+	famHsrpCfgPriority = &metricFamily{Name: "cisco_hsrp_configured_priority", Type: "gauge",
+		Help: "Configured priority of the switch in the HSRP group.", Labels: hsrpLabels}
+	famHsrpStateChanges = &metricFamily{Name: "cisco_hsrp_state_changes", Type: "counter",
+		Help: "Number of times the HSRP group changed state.", Labels: hsrpLabels}
+	famHsrpLastChange = &metricFamily{Name: "cisco_hsrp_last_state_change_seconds", Type: "gauge",
+		Help: "Seconds since the HSRP group last changed state.", Labels: hsrpLabels}
+)
+
+// HSRP group states and priorities from "show hsrp"
+func collectHsrp(ms *metricSet, hsrp *client.ShowHsrpResponseResult) {
+	for _, t := range hsrp.Body.TableGrpDetail {
+		for _, r := range t.RowGrpDetail {
+			vip := r.ShVip
+			if vip == "" {
+				vip = r.ShVipV6
+			}
+			group := fmt.Sprint(r.ShGroupNum)
+
+			// Older releases spell out the initial state
+			state := r.ShGroupState
+			if state == "Initial" {
+				state = "Init"
+			}
+
+			ms.AddState(famHsrpState, isState(state, "Active"), state, r.ShIfIndex, group, vip)
+			ms.Add(famHsrpPriority, float64(r.ShPrio), r.ShIfIndex, group, vip)
+			ms.Add(famHsrpCfgPriority, float64(r.ShCfgPrio), r.ShIfIndex, group, vip)
+			ms.Add(famHsrpStateChanges, float64(r.ShNumOfTotalStateChanges), r.ShIfIndex, group, vip)
+			ms.Add(famHsrpLastChange, float64(r.ShLastTotalStateChange), r.ShIfIndex, group, vip)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// A counter of "show interface" and how to read it from the interface model
+type intfCounter struct {
+	field string
+	fam   *metricFamily
+	value func(i *client.Interface) uint64
+}
+
+func newIntfCounter(field, help string, value func(i *client.Interface) uint64) *intfCounter {
+	return &intfCounter{
+		field: field,
+		fam:   &metricFamily{Name: "cisco_interface_" + field, Type: "counter", Help: help, Labels: []string{"interface"}},
+		value: value,
+	}
+}
+
+// Counters of "show interface", the field is the name used for them in
+// interface_counters
+var intfCounters = []*intfCounter{
+	newIntfCounter("input_bytes", "Bytes received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputBytes }),
+	newIntfCounter("input_ucast_bytes", "Unicast bytes received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputUnicastBytes }),
+	newIntfCounter("input_packets", "Packets received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputPackets }),
+	newIntfCounter("input_ucast_packets", "Unicast packets received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputUnicastPackets }),
+	newIntfCounter("input_bcast_packets", "Broadcast packets received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputBroadcastPackets }),
+	newIntfCounter("input_mcast_packets", "Multicast packets received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputMulticastPackets }),
+	newIntfCounter("input_jumbo_packets", "Jumbo packets received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputJumboPackets }),
+	newIntfCounter("input_compressed", "Compressed packets received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputCompressed }),
+	newIntfCounter("input_errors", "Input errors on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputErrors }),
+	newIntfCounter("input_frame_errors", "Frames received on the interface with a framing error.",
+		func(i *client.Interface) uint64 { return i.Counters.InputFrameErrors }),
+	newIntfCounter("input_discards", "Packets received on the interface and discarded.",
+		func(i *client.Interface) uint64 { return i.Counters.InputDiscards }),
+	newIntfCounter("input_pause", "Pause frames received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputPause }),
+	newIntfCounter("input_overruns", "Input overruns on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputOverruns }),
+	newIntfCounter("input_iface_down_drops", "Packets dropped on input while the interface was down.",
+		func(i *client.Interface) uint64 { return i.Counters.InputIfaceDownDrops }),
+	newIntfCounter("input_fifo", "Input FIFO errors on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.InputFifo }),
+	newIntfCounter("output_bytes", "Bytes sent on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputBytes }),
+	newIntfCounter("output_ucast_bytes", "Unicast bytes sent on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputUnicastBytes }),
+	newIntfCounter("output_packets", "Packets sent on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputPackets }),
+	newIntfCounter("output_ucast_packets", "Unicast packets sent on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputUnicastPackets }),
+	newIntfCounter("output_bcast_packets", "Broadcast packets sent on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputBroadcastPackets }),
+	newIntfCounter("output_mcast_packets", "Multicast packets sent on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputMulticastPackets }),
+	newIntfCounter("output_jumbo_packets", "Jumbo packets sent on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputJumboPackets }),
+	newIntfCounter("output_errors", "Output errors on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputErrors }),
+	newIntfCounter("output_discards", "Packets discarded on the way out of the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputDiscards }),
+	newIntfCounter("output_pause", "Pause frames sent on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputPause }),
+	newIntfCounter("output_underruns", "Output underruns on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputUnderruns }),
+	newIntfCounter("output_carrier_errors", "Output carrier errors on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputCarrierErrors }),
+	newIntfCounter("output_fifo", "Output FIFO errors on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.OutputFifo }),
+	newIntfCounter("babbles", "Babbles on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.Babbles }),
+	newIntfCounter("bad_ethtype_drops", "Packets dropped for a bad ethertype.",
+		func(i *client.Interface) uint64 { return i.Counters.BadEtherTypeDrops }),
+	newIntfCounter("bad_proto_drops", "Packets dropped for a bad protocol.",
+		func(i *client.Interface) uint64 { return i.Counters.BadProtocolDrops }),
+	newIntfCounter("no_carrier", "No carrier events on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.NoCarrier }),
+	newIntfCounter("lost_carrier", "Lost carrier events on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.LostCarrier }),
+	newIntfCounter("dribble", "Packets received on the interface with a dribble condition.",
+		func(i *client.Interface) uint64 { return i.Counters.Dribble }),
+	newIntfCounter("collisions", "Collisions on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.Collisions }),
+	newIntfCounter("late_collisions", "Late collisions on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.LateCollisions }),
+	newIntfCounter("deferred", "Deferred transmissions on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.Deferred }),
+	newIntfCounter("watchdog", "Watchdog events on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.Watchdog }),
+	newIntfCounter("storm_suppression", "Packets dropped by storm suppression.",
+		func(i *client.Interface) uint64 { return i.Counters.StormSuppression }),
+	newIntfCounter("ignored", "Packets ignored by the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.Ignored }),
+	newIntfCounter("runts", "Runt frames received on the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.Runts }),
+	newIntfCounter("crc_errors", "Frames received on the interface with a CRC error.",
+		func(i *client.Interface) uint64 { return i.Counters.CrcErrors }),
+	newIntfCounter("no_buffer", "Packets received on the interface with no buffer available.",
+		func(i *client.Interface) uint64 { return i.Counters.NoBufferReceivedErrors }),
+	newIntfCounter("resets", "Resets of the interface.",
+		func(i *client.Interface) uint64 { return i.Counters.Resets }),
+}
+
+// The rate and load gauges of the three load intervals, selected in
+// interface_counters with the names rate_bits, rate_packets and load
+var (
+	intfRateLabels  = []string{"interface", "interval", "direction"}
+	famIntfRateBits = &metricFamily{Name: "cisco_interface_rate_bits_per_second", Type: "gauge",
+		Help: "Bit rate of the interface over the load interval.", Labels: intfRateLabels}
+	famIntfRatePkts = &metricFamily{Name: "cisco_interface_rate_packets_per_second", Type: "gauge",
+		Help: "Packet rate of the interface over the load interval.", Labels: intfRateLabels}
+	famIntfLoad = &metricFamily{Name: "cisco_interface_load", Type: "gauge",
+		Help: "Load of the interface over the load interval, out of 255.", Labels: intfRateLabels}
+)
+
+var intfIntervalFields = map[string]bool{"rate_bits": true, "rate_packets": true, "load": true}
+
+func findIntfCounter(field string) *intfCounter {
+	for _, c := range intfCounters {
+		if c.field == field {
+			return c
+		}
+	}
+	return nil
+}
+
+// Check the names in interface_counters
+func checkIntfCounters(names []string) error {
+	for _, name := range names {
+		if findIntfCounter(name) == nil && !intfIntervalFields[name] {
+			return fmt.Errorf("unknown interface counter %q", name)
+		}
+	}
+	return nil
+}
+
+// Counters and rates of every interface from "show interface".  The fields
+// are the ones listed in interface_counters, or all of them when none are.
+func collectInterfaces(ms *metricSet, intfs []*client.Interface, fields []string) {
+	var counters []*intfCounter
+	want := make(map[string]bool)
+	if len(fields) == 0 {
+		counters = intfCounters
+		for name := range intfIntervalFields {
+			want[name] = true
+		}
+	} else {
+		for _, name := range fields {
+			if c := findIntfCounter(name); c != nil {
+				counters = append(counters, c)
+			}
+			want[name] = true
+		}
+	}
+
+	for _, c := range counters {
+		for _, intf := range intfs {
+			ms.Add(c.fam, float64(c.value(intf)), intf.Name)
+		}
+	}
+
+	for _, intf := range intfs {
+		iv := &intf.Counters.Intervals
+		for n, v := range []struct{ inBits, inPkts, outBits, outPkts, rx, tx uint64 }{
+			{iv.Interval1.InputRateBits, iv.Interval1.InputRatePackets, iv.Interval1.OutputRateBits,
+				iv.Interval1.OutputRatePackets, iv.Interval1.RxLoad, iv.Interval1.TxLoad},
+			{iv.Interval2.InputRateBits, iv.Interval2.InputRatePackets, iv.Interval2.OutputRateBits,
+				iv.Interval2.OutputRatePackets, iv.Interval2.RxLoad, iv.Interval2.TxLoad},
+			{iv.Interval3.InputRateBits, iv.Interval3.InputRatePackets, iv.Interval3.OutputRateBits,
+				iv.Interval3.OutputRatePackets, iv.Interval3.RxLoad, iv.Interval3.TxLoad},
+		} {
+			interval := fmt.Sprint(n + 1)
+			if want["rate_bits"] {
+				ms.Add(famIntfRateBits, float64(v.inBits), intf.Name, interval, "in")
+				ms.Add(famIntfRateBits, float64(v.outBits), intf.Name, interval, "out")
+			}
+			if want["rate_packets"] {
+				ms.Add(famIntfRatePkts, float64(v.inPkts), intf.Name, interval, "in")
+				ms.Add(famIntfRatePkts, float64(v.outPkts), intf.Name, interval, "out")
+			}
+			if want["load"] {
+				ms.Add(famIntfLoad, float64(v.rx), intf.Name, interval, "in")
+				ms.Add(famIntfLoad, float64(v.tx), intf.Name, interval, "out")
+			}
+		}
+	}
+}
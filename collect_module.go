@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show module"
+var (
+	famModuleInfo = &metricFamily{Name: "cisco_module_info", Type: "info",
+		Help:   "Inventory of the module in the slot.",
+		Labels: []string{"module", "type", "model", "ports", "serial", "mac", "hw", "sw", "slottype"}}
+	famModuleStatus = &metricFamily{Name: "cisco_module_status", Type: "stateset",
+		Help: "Status of the module, 1 when ok.", Labels: []string{"module", "model"},
+		States: []string{"ok", "active", "ha-standby", "standby", "powered-up", "powered-dn",
+			"pwr-denied", "testing", "fail"}}
+	famModuleDiag = &metricFamily{Name: "cisco_module_diag_status", Type: "stateset",
+		Help: "Result of the online diagnostics of the module, 1 when passed.", Labels: []string{"module"},
+		States: []string{"Pass", "Fail", "Untested"}}
+	famModulePower = &metricFamily{Name: "cisco_module_power_status", Type: "stateset",
+		Help: "Power status of the module, 1 when powered up.", Labels: []string{"module", "reason"},
+		States: []string{"powered-up", "powered-dn", "pwr-denied", "pwr-cycld"}}
+)
+
+// Module states which count as healthy
+var moduleOK = map[string]bool{"ok": true, "active": true, "ha-standby": true, "standby": true}
+
+// Status and inventory of the line cards and supervisors from "show module"
+func collectModules(ms *metricSet, mod *client.ShowModuleResponseResult) {
+	b := &mod.Body
+
+	// The tables list the same modules but not always all of them or in the
+	// same order, so they are joined on the module number
+	type macInfo struct{ mac, serial string }
+	type wwnInfo struct{ hw, sw, slottype string }
+	macs := make(map[int]macInfo)
+	for _, t := range b.TableModmacinfo {
+		for _, r := range t.RowModmacinfo {
+			macs[r.Modmac] = macInfo{r.Mac, r.Serialnum}
+		}
+	}
+	wwns := make(map[int]wwnInfo)
+	for _, t := range b.TableModwwninfo {
+		for _, r := range t.RowModwwninfo {
+			wwns[r.Modwwn] = wwnInfo{r.Hw, r.Sw, r.Slottype}
+		}
+	}
+
+	for _, t := range b.TableModinfo {
+		for _, r := range t.RowModinfo {
+			module := fmt.Sprint(r.Modinf)
+			mac, wwn := macs[r.Modinf], wwns[r.Modinf]
+			ms.Add(famModuleInfo, 1, module, r.Modtype, r.Model, fmt.Sprint(r.Ports),
+				mac.serial, mac.mac, wwn.hw, wwn.sw, wwn.slottype)
+
+			// The supervisor in charge is shown as "active *", and some
+			// releases spell out a failed module as "failure"
+			status := strings.TrimSpace(strings.TrimSuffix(r.Status, "*"))
+			if status == "failure" {
+				status = "fail"
+			}
+			ok := 0.0
+			if moduleOK[status] {
+				ok = 1
+			}
+			ms.AddState(famModuleStatus, ok, status, module, r.Model)
+		}
+	}
+
+	for _, t := range b.TableModdiaginfo {
+		for _, r := range t.RowModdiaginfo {
+			ms.AddState(famModuleDiag, isState(r.Diagstatus, "Pass"), r.Diagstatus, fmt.Sprint(r.Mod))
+		}
+	}
+	for _, t := range b.TableModpwrinfo {
+		for _, r := range t.RowModpwrinfo {
+			ms.AddState(famModulePower, isState(r.Pwrstat, "powered-up"), r.Pwrstat,
+				fmt.Sprint(r.Modpwr), r.Reason)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show ntp peer-status"
+var (
+	ntpLabels         = []string{"remote", "vrf"}
+	famNtpPeerStratum = &metricFamily{Name: "cisco_ntp_peer_stratum", Type: "gauge",
+		Help: "Stratum of the NTP peer.", Labels: ntpLabels}
+	famNtpPeerPoll = &metricFamily{Name: "cisco_ntp_peer_poll_seconds", Type: "gauge",
+		Help: "Poll interval of the NTP peer in seconds.", Labels: ntpLabels}
+	famNtpPeerDelay = &metricFamily{Name: "cisco_ntp_peer_delay_seconds", Type: "gauge",
+		Help: "Round trip delay to the NTP peer in seconds.", Labels: ntpLabels}
+	famNtpPeerReach = &metricFamily{Name: "cisco_ntp_peer_reach", Type: "gauge",
+		Help: "Number of the last 8 polls of the NTP peer which were answered.", Labels: ntpLabels}
+	famNtpPeerSelected = &metricFamily{Name: "cisco_ntp_peer_selected", Type: "gauge",
+		Help: "Whether the clock is synchronized to the NTP peer.", Labels: ntpLabels}
+	famNtpSynced = &metricFamily{Name: "cisco_ntp_synced", Type: "gauge",
+		Help: "Whether the clock is synchronized to any NTP peer."}
+)
+
+// NTP peers and synchronization from "show ntp peer-status"
+func collectNtp(ms *metricSet, ntp *client.ShowNtpPeerStatusResponseResult) {
+	synced := 0.0
+	for _, r := range ntp.Flat() {
+		remote := strings.TrimSpace(r.Remote)
+		ms.Add(famNtpPeerStratum, float64(r.St), remote, r.Vrf)
+		ms.Add(famNtpPeerPoll, float64(r.Poll), remote, r.Vrf)
+		ms.Add(famNtpPeerDelay, float32Value(r.Delay), remote, r.Vrf)
+
+		// The reach is an octal shift register with a bit for each poll
+		if reach, err := strconv.ParseUint(strings.TrimSpace(r.Reach), 8, 8); err == nil {
+			ms.Add(famNtpPeerReach, float64(bits.OnesCount8(uint8(reach))), remote, r.Vrf)
+		}
+
+		// The peer the clock is synchronized to is marked with a *
+		selected := 0.0
+		if strings.TrimSpace(r.Syncmode) == "*" {
+			selected, synced = 1, 1
+		}
+		ms.Add(famNtpPeerSelected, selected, remote, r.Vrf)
+	}
+	ms.Add(famNtpSynced, synced)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+)
+
+// Metric families reported from "show ip ospf neighbors detail vrf all"
+var (
+	ospfLabels   = []string{"process", "vrf", "neighbor", "address", "interface", "area"}
+	famOspfState = &metricFamily{Name: "cisco_ospf_neighbor_state", Type: "stateset",
+		Help: "Adjacency state of the OSPF neighbor, 1 when full.", Labels: ospfLabels,
+		States: []string{"down", "attempt", "init", "2way", "exstart", "exchange", "loading", "full"}}
+	famOspfUptime = &metricFamily{Name: "cisco_ospf_neighbor_uptime_seconds", Type: "gauge",
+		Help: "Seconds since the OSPF neighbor came up.", Labels: ospfLabels}
+	famOspfPriority = &metricFamily{Name: "cisco_ospf_neighbor_priority", Type: "gauge",
+		Help: "Router priority of the OSPF neighbor.", Labels: ospfLabels}
+	famOspfDeadTimer = &metricFamily{Name: "cisco_ospf_neighbor_dead_timer_seconds", Type: "gauge",
+		Help: "Seconds left before the OSPF neighbor is declared down.", Labels: ospfLabels}
+	famOspfCount = &metricFamily{Name: "cisco_ospf_neighbor_count", Type: "gauge",
+		Help: "Number of OSPF neighbors of the process in the VRF.", Labels: []string{"process", "vrf"}}
+)
+
+// Reply to "show ip ospf neighbors detail vrf all", which the client has no
+// model for.  The area and dead timer are only filled in by the detail form.
+type ospfResult struct {
+	Body struct {
+		TableCtx []struct {
+			RowCtx []struct {
+				Ptag     string `json:"ptag"`
+				Cname    string `json:"cname"`
+				Nbrcount int    `json:"nbrcount"`
+				TableNbr []struct {
+					RowNbr []struct {
+						Rid       string   `json:"rid"`
+						Priority  int      `json:"priority"`
+						State     string   `json:"state"`
+						Drstate   string   `json:"drstate"`
+						Uptime    duration `json:"uptime"`
+						Addr      string   `json:"addr"`
+						Intf      string   `json:"intf"`
+						Area      string   `json:"area"`
+						Deadtimer duration `json:"deadtimer"`
+					} `json:"ROW_nbr"`
+				} `json:"TABLE_nbr"`
+			} `json:"ROW_ctx"`
+		} `json:"TABLE_ctx"`
+	} `json:"body"`
+}
+
+// A neighbor with the process and VRF it is in.  A process without neighbors
+// gets a row of its own with no Rid, so its count is not lost.
+type ospfResultFlat struct {
+	Ptag      string
+	Cname     string
+	Nbrcount  int
+	Rid       string
+	Priority  int
+	State     string
+	Drstate   string
+	Uptime    duration
+	Addr      string
+	Intf      string
+	Area      string
+	Deadtimer duration
+}
+
+func newOspfResult(b []byte) (*ospfResult, error) {
+	ospf := &ospfResult{}
+	if err := decodeResult(b, ospf); err != nil {
+		return nil, err
+	}
+	return ospf, nil
+}
+
+func (d *ospfResult) Flat() (out []ospfResultFlat) {
+	for _, Tc := range d.Body.TableCtx {
+		for _, Rc := range Tc.RowCtx {
+			var neighbors int
+			for _, Tn := range Rc.TableNbr {
+				for _, Rn := range Tn.RowNbr {
+					neighbors++
+					out = append(out, ospfResultFlat{
+						Ptag:      Rc.Ptag,
+						Cname:     Rc.Cname,
+						Nbrcount:  Rc.Nbrcount,
+						Rid:       Rn.Rid,
+						Priority:  Rn.Priority,
+						State:     Rn.State,
+						Drstate:   Rn.Drstate,
+						Uptime:    Rn.Uptime,
+						Addr:      Rn.Addr,
+						Intf:      Rn.Intf,
+						Area:      Rn.Area,
+						Deadtimer: Rn.Deadtimer,
+					})
+				}
+			}
+			if neighbors == 0 {
+				out = append(out, ospfResultFlat{Ptag: Rc.Ptag, Cname: Rc.Cname, Nbrcount: Rc.Nbrcount})
+			}
+		}
+	}
+	return
+}
+
+// OSPF neighbors of every process and VRF from
+// "show ip ospf neighbors detail vrf all"
+func collectOspf(ms *metricSet, ospf *ospfResult) {
+	var last ospfResultFlat
+	for i, r := range ospf.Flat() {
+		// The rows of a process come together, it is counted at the first
+		if i == 0 || r.Ptag != last.Ptag || r.Cname != last.Cname {
+			ms.Add(famOspfCount, float64(r.Nbrcount), r.Ptag, r.Cname)
+		}
+		last = r
+		if r.Rid == "" {
+			continue
+		}
+
+		lbl := []string{r.Ptag, r.Cname, r.Rid, r.Addr, longInterfaceName(r.Intf), r.Area}
+		// The JSON spells out the 2-way state
+		state := strings.ToLower(r.State)
+		if state == "twoway" {
+			state = "2way"
+		}
+		ms.AddState(famOspfState, isState(state, "full"), state, lbl...)
+		ms.Add(famOspfUptime, float64(r.Uptime/1e9), lbl...)
+		ms.Add(famOspfPriority, float64(r.Priority), lbl...)
+		ms.Add(famOspfDeadTimer, float64(r.Deadtimer/1e9), lbl...)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCollectOspf(t *testing.T) {
+	dat, err := ioutil.ReadFile("testdata/show_ip_ospf_neighbors_detail_vrf_all.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ospf, err := newOspfResult(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := newMetricSet()
+	collectOspf(ms, ospf)
+
+	got := string(ms.Format(formatOpenMetrics))
+	for _, want := range []string{
+		`cisco_ospf_neighbor_count{process="UNDERLAY",vrf="default"} 2`,
+		`cisco_ospf_neighbor_count{process="UNDERLAY",vrf="management"} 1`,
+		`cisco_ospf_neighbor_count{process="UNDERLAY",vrf="blue"} 0`,
+		`cisco_ospf_neighbor_state{process="UNDERLAY",vrf="default",neighbor="10.0.0.2",address="10.1.1.2",interface="Ethernet1/1",area="0.0.0.0",cisco_ospf_neighbor_state="full"} 1`,
+		`cisco_ospf_neighbor_state{process="UNDERLAY",vrf="default",neighbor="10.0.0.3",address="10.1.2.3",interface="Vlan100",area="0.0.0.10",cisco_ospf_neighbor_state="2way"} 1`,
+		`cisco_ospf_neighbor_state{process="UNDERLAY",vrf="management",neighbor="192.168.0.254",address="192.168.0.254",interface="mgmt0",area="0.0.0.0",cisco_ospf_neighbor_state="exstart"} 1`,
+		`cisco_ospf_neighbor_uptime_seconds{process="UNDERLAY",vrf="default",neighbor="10.0.0.2",address="10.1.1.2",interface="Ethernet1/1",area="0.0.0.0"} 93784`,
+		`cisco_ospf_neighbor_uptime_seconds{process="UNDERLAY",vrf="default",neighbor="10.0.0.3",address="10.1.2.3",interface="Vlan100",area="0.0.0.10"} 750`,
+		`cisco_ospf_neighbor_priority{process="UNDERLAY",vrf="default",neighbor="10.0.0.3",address="10.1.2.3",interface="Vlan100",area="0.0.0.10"} 0`,
+		`cisco_ospf_neighbor_dead_timer_seconds{process="UNDERLAY",vrf="management",neighbor="192.168.0.254",address="192.168.0.254",interface="mgmt0",area="0.0.0.0"} 38`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if n := strings.Count(got, "cisco_ospf_neighbor_state{"); n != 3*len(famOspfState.States) {
+		t.Errorf("got %d state series, want %d", n, 3*len(famOspfState.States))
+	}
+}
+
+func TestOspfFlat(t *testing.T) {
+	dat, err := ioutil.ReadFile("testdata/show_ip_ospf_neighbors_detail_vrf_all.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ospf, err := newOspfResult(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := ospf.Flat()
+
+	want := []ospfResultFlat{
+		{Ptag: "UNDERLAY", Cname: "default", Nbrcount: 2, Rid: "10.0.0.2", Priority: 1, State: "FULL", Drstate: "DR",
+			Uptime: 93784e9, Addr: "10.1.1.2", Intf: "Eth1/1", Area: "0.0.0.0", Deadtimer: 35e9},
+		{Ptag: "UNDERLAY", Cname: "default", Nbrcount: 2, Rid: "10.0.0.3"},
+		{Ptag: "UNDERLAY", Cname: "management", Nbrcount: 1, Rid: "192.168.0.254"},
+		{Ptag: "UNDERLAY", Cname: "blue"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	if rows[0] != want[0] {
+		t.Errorf("first row:\n%+v\nwant:\n%+v", rows[0], want[0])
+	}
+	for i, r := range rows {
+		if r.Ptag != want[i].Ptag || r.Cname != want[i].Cname || r.Nbrcount != want[i].Nbrcount || r.Rid != want[i].Rid {
+			t.Errorf("row %d is %s/%s/%d/%q, want %s/%s/%d/%q", i, r.Ptag, r.Cname, r.Nbrcount, r.Rid,
+				want[i].Ptag, want[i].Cname, want[i].Nbrcount, want[i].Rid)
+		}
+	}
+}
+
+func TestCollectOspfEmptyTimers(t *testing.T) {
+	// A neighbor which is still coming up may have no uptime or dead timer
+	ospf, err := newOspfResult([]byte(`{"body": {"TABLE_ctx": {"ROW_ctx": {"ptag": "1", "cname": "default", "nbrcount": "1",
+		"TABLE_nbr": {"ROW_nbr": {"rid": "10.0.0.9", "priority": "1", "state": "INIT", "uptime": "", "addr": "10.9.9.9",
+		"intf": "Eth1/9", "area": "0.0.0.0", "deadtimer": ""}}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := newMetricSet()
+	collectOspf(ms, ospf)
+
+	got := string(ms.Format(formatText))
+	for _, want := range []string{
+		`cisco_ospf_neighbor_uptime_seconds{process="1",vrf="default",neighbor="10.0.0.9",address="10.9.9.9",interface="Ethernet1/9",area="0.0.0.0"} 0`,
+		`cisco_ospf_neighbor_dead_timer_seconds{process="1",vrf="default",neighbor="10.0.0.9",address="10.9.9.9",interface="Ethernet1/9",area="0.0.0.0"} 0`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, got)
+		}
+	}
+}
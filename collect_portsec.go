@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show port-security address"
+var (
+	famPortSecAddrs = &metricFamily{Name: "cisco_port_security_addresses", Type: "gauge",
+		Help: "Secure MAC addresses in use on the switch."}
+	famPortSecMax = &metricFamily{Name: "cisco_port_security_max_addresses", Type: "gauge",
+		Help: "Maximum number of secure MAC addresses the switch allows."}
+	famPortSecIntf = &metricFamily{Name: "cisco_port_security_interface_addresses", Type: "gauge",
+		Help: "Secure MAC addresses in use on the interface.", Labels: []string{"interface"}}
+	famPortSecVlan = &metricFamily{Name: "cisco_port_security_vlan_addresses", Type: "gauge",
+		Help: "Secure MAC addresses in use in the VLAN.", Labels: []string{"vlan"}}
+
+	// Only with port_security_entries, a series for every secure address
+	portSecEntryLabels = []string{"interface", "vlan", "mac", "type"}
+	famPortSecEntry    = &metricFamily{Name: "cisco_port_security_address_info", Type: "info",
+		Help: "Secure MAC address learned on the interface.", Labels: portSecEntryLabels}
+	famPortSecAge = &metricFamily{Name: "cisco_port_security_address_remaining_age_seconds", Type: "gauge",
+		Help: "Seconds left before the secure MAC address ages out.", Labels: portSecEntryLabels}
+)
+
+// Secure MAC addresses from "show port-security address", counted per
+// interface and VLAN so the number of series stays bounded.  The addresses
+// themselves are only exported when entries is set.
+func collectPortSecurity(ms *metricSet, psec *client.ShowPortSecurityAddressResponseResult, entries bool) {
+	ms.Add(famPortSecAddrs, float64(psec.Body.TotalAddr))
+	ms.Add(famPortSecMax, float64(psec.Body.MaxSysLimit))
+
+	var intfs, vlans rowCount
+	for _, r := range psec.Flat() {
+		intf, vlan := longInterfaceName(r.IfIndex), fmt.Sprint(r.VlanID)
+		intfs.Add(intf)
+		vlans.Add(vlan)
+
+		if entries {
+			lbl := []string{intf, vlan, r.MacAddr, strings.ToLower(r.Type)}
+			ms.Add(famPortSecEntry, 1, lbl...)
+			// The switch reports the remaining age in minutes, 0 when the
+			// address does not age
+			ms.Add(famPortSecAge, float64(r.RemainAge*60), lbl...)
+		}
+	}
+	intfs.AddTo(ms, famPortSecIntf)
+	vlans.AddTo(ms, famPortSecVlan)
+}
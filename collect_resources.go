@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show system resources"
+var (
+	famLoadAverage = &metricFamily{Name: "cisco_load_average", Type: "gauge",
+		Help: "Load average of the control plane over the period.", Labels: []string{"period"}}
+	famProcesses = &metricFamily{Name: "cisco_processes", Type: "gauge",
+		Help: "Number of processes on the control plane.", Labels: []string{"state"}}
+
+	famCPUUsage = &metricFamily{Name: "cisco_cpu_usage_percent", Type: "gauge",
+		Help: "Share of time all the CPUs spent in the mode, in percent.", Labels: []string{"mode"}}
+	famCPUCoreUsage = &metricFamily{Name: "cisco_cpu_core_usage_percent", Type: "gauge",
+		Help: "Share of time the CPU spent in the mode, in percent.", Labels: []string{"cpu", "mode"}}
+
+	famMemoryTotal = &metricFamily{Name: "cisco_memory_usage_total_bytes", Type: "gauge",
+		Help: "Memory of the control plane in bytes."}
+	famMemoryUsed = &metricFamily{Name: "cisco_memory_usage_used_bytes", Type: "gauge",
+		Help: "Memory of the control plane in use in bytes."}
+	famMemoryFree = &metricFamily{Name: "cisco_memory_usage_free_bytes", Type: "gauge",
+		Help: "Memory of the control plane still free in bytes."}
+	famMemoryStatus = &metricFamily{Name: "cisco_memory_usage_status", Type: "stateset",
+		Help:   "Memory alert level of the control plane, 1 when ok.",
+		States: []string{"ok", "minor", "severe", "critical"}}
+)
+
+// CPU, memory and load of the control plane from "show system resources"
+func collectSystemResources(ms *metricSet, res *client.ShowSystemResourcesResponseResult) {
+	b := &res.Body
+	ms.Add(famLoadAverage, float32Value(b.LoadAvg1Min), "1m")
+	ms.Add(famLoadAverage, float32Value(b.LoadAvg5Min), "5m")
+	ms.Add(famLoadAverage, float32Value(b.LoadAvg15Min), "15m")
+	ms.Add(famProcesses, float64(b.ProcessesTotal), "total")
+	ms.Add(famProcesses, float64(b.ProcessesRunning), "running")
+
+	ms.Add(famCPUUsage, float32Value(b.CPUStateUser), "user")
+	ms.Add(famCPUUsage, float32Value(b.CPUStateKernel), "kernel")
+	ms.Add(famCPUUsage, float32Value(b.CPUStateIdle), "idle")
+	for _, t := range b.TableCPUUsage {
+		for _, r := range t.RowCPUUsage {
+			cpu := fmt.Sprint(r.Cpuid)
+			ms.Add(famCPUCoreUsage, float32Value(r.User), cpu, "user")
+			ms.Add(famCPUCoreUsage, float32Value(r.Kernel), cpu, "kernel")
+			ms.Add(famCPUCoreUsage, float32Value(r.Idle), cpu, "idle")
+		}
+	}
+
+	// The switch reports memory in kilobytes
+	ms.Add(famMemoryTotal, float64(b.MemoryUsageTotal)*1024)
+	ms.Add(famMemoryUsed, float64(b.MemoryUsageUsed)*1024)
+	ms.Add(famMemoryFree, float64(b.MemoryUsageFree)*1024)
+	if b.CurrentMemoryStatus != "" {
+		status := strings.ToLower(b.CurrentMemoryStatus)
+		ms.AddState(famMemoryStatus, okState(status), status)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Metric families reported from "show interface transceiver details"
+var (
+	famXcvrInfo = &metricFamily{Name: "cisco_transceiver_info", Type: "info",
+		Help: "Inventory of the transceiver plugged into the interface.",
+		Labels: []string{"interface", "type", "vendor", "partnum", "rev", "serial",
+			"cisco_part_number", "cisco_pid"}}
+
+	xcvrLabels    = []string{"interface", "lane"}
+	xcvrThrLabels = []string{"interface", "lane", "threshold"}
+	xcvrFlags     = []string{"ok", "high-alarm", "high-warning", "low-warning", "low-alarm"}
+
+	famXcvrTemp = &metricFamily{Name: "cisco_transceiver_temperature_celsius", Type: "gauge",
+		Help: "Temperature of the transceiver in degrees celsius.", Labels: xcvrLabels}
+	famXcvrTempThr = &metricFamily{Name: "cisco_transceiver_temperature_threshold_celsius", Type: "gauge",
+		Help: "Alarm and warning thresholds of the transceiver temperature.", Labels: xcvrThrLabels}
+	famXcvrTempFlag = &metricFamily{Name: "cisco_transceiver_temperature_flag", Type: "stateset",
+		Help: "Threshold crossed by the transceiver temperature, 1 when ok.", Labels: xcvrLabels, States: xcvrFlags}
+	famXcvrVolt = &metricFamily{Name: "cisco_transceiver_voltage_volts", Type: "gauge",
+		Help: "Supply voltage of the transceiver in volts.", Labels: xcvrLabels}
+	famXcvrVoltThr = &metricFamily{Name: "cisco_transceiver_voltage_threshold_volts", Type: "gauge",
+		Help: "Alarm and warning thresholds of the transceiver voltage.", Labels: xcvrThrLabels}
+	famXcvrVoltFlag = &metricFamily{Name: "cisco_transceiver_voltage_flag", Type: "stateset",
+		Help: "Threshold crossed by the transceiver voltage, 1 when ok.", Labels: xcvrLabels, States: xcvrFlags}
+	famXcvrCurrent = &metricFamily{Name: "cisco_transceiver_current_amperes", Type: "gauge",
+		Help: "Laser bias current of the lane in amperes.", Labels: xcvrLabels}
+	famXcvrCurrentThr = &metricFamily{Name: "cisco_transceiver_current_threshold_amperes", Type: "gauge",
+		Help: "Alarm and warning thresholds of the laser bias current.", Labels: xcvrThrLabels}
+	famXcvrCurrentFlag = &metricFamily{Name: "cisco_transceiver_current_flag", Type: "stateset",
+		Help: "Threshold crossed by the laser bias current, 1 when ok.", Labels: xcvrLabels, States: xcvrFlags}
+	famXcvrTxPwr = &metricFamily{Name: "cisco_transceiver_tx_power_dbm", Type: "gauge",
+		Help: "Transmit power of the lane in dBm.", Labels: xcvrLabels}
+	famXcvrTxPwrThr = &metricFamily{Name: "cisco_transceiver_tx_power_threshold_dbm", Type: "gauge",
+		Help: "Alarm and warning thresholds of the transmit power.", Labels: xcvrThrLabels}
+	famXcvrTxPwrFlag = &metricFamily{Name: "cisco_transceiver_tx_power_flag", Type: "stateset",
+		Help: "Threshold crossed by the transmit power, 1 when ok.", Labels: xcvrLabels, States: xcvrFlags}
+	famXcvrRxPwr = &metricFamily{Name: "cisco_transceiver_rx_power_dbm", Type: "gauge",
+		Help: "Receive power of the lane in dBm.", Labels: xcvrLabels}
+	famXcvrRxPwrThr = &metricFamily{Name: "cisco_transceiver_rx_power_threshold_dbm", Type: "gauge",
+		Help: "Alarm and warning thresholds of the receive power.", Labels: xcvrThrLabels}
+	famXcvrRxPwrFlag = &metricFamily{Name: "cisco_transceiver_rx_power_flag", Type: "stateset",
+		Help: "Threshold crossed by the receive power, 1 when ok.", Labels: xcvrLabels, States: xcvrFlags}
+)
+
+// Reply to "show interface transceiver details".  The client has the readings
+// as int, which cannot hold the likes of 35.21 celsius or -2.34 dBm, nor tell
+// a missing one from zero.
+type transceiverResult struct {
+	Body struct {
+		TableInterface []struct {
+			RowInterface []transceiverRow `json:"ROW_interface"`
+		} `json:"TABLE_interface"`
+	} `json:"body"`
+}
+
+type transceiverRow struct {
+	Interface       string `json:"interface"`
+	Sfp             string `json:"sfp"`
+	Type            string `json:"type"`
+	Name            string `json:"name"`
+	PartNum         string `json:"partnum"`
+	Rev             string `json:"rev"`
+	SerialNum       string `json:"serialnum"`
+	CiscoPartNumber string `json:"cisco_part_number"`
+	CiscoProductID  string `json:"cisco_product_id"`
+	TableLane       []struct {
+		RowLane []transceiverLane `json:"ROW_lane"`
+	} `json:"TABLE_lane"`
+}
+
+type transceiverLane struct {
+	LaneNumber    int      `json:"lane_number"`
+	Temperature   reading  `json:"temperature"`
+	TempAlrmHi    reading  `json:"temp_alrm_hi"`
+	TempAlrmLo    reading  `json:"temp_alrm_lo"`
+	TempWarnHi    reading  `json:"temp_warn_hi"`
+	TempWarnLo    reading  `json:"temp_warn_lo"`
+	Voltage       reading  `json:"voltage"`
+	VoltAlrmHi    reading  `json:"volt_alrm_hi"`
+	VoltAlrmLo    reading  `json:"volt_alrm_lo"`
+	VoltWarnHi    reading  `json:"volt_warn_hi"`
+	VoltWarnLo    reading  `json:"volt_warn_lo"`
+	Current       reading  `json:"current"`
+	CurrentAlrmHi reading  `json:"current_alrm_hi"`
+	CurrentAlrmLo reading  `json:"current_alrm_lo"`
+	CurrentWarnHi reading  `json:"current_warn_hi"`
+	CurrentWarnLo reading  `json:"current_warn_lo"`
+	TxPwr         reading  `json:"tx_pwr"`
+	TxPwrAlrmHi   reading  `json:"tx_pwr_alrm_hi"`
+	TxPwrAlrmLo   reading  `json:"tx_pwr_alrm_lo"`
+	TxPwrWarnHi   reading  `json:"tx_pwr_warn_hi"`
+	TxPwrWarnLo   reading  `json:"tx_pwr_warn_lo"`
+	RxPwr         reading  `json:"rx_pwr"`
+	RxPwrAlrmHi   reading  `json:"rx_pwr_alrm_hi"`
+	RxPwrAlrmLo   reading  `json:"rx_pwr_alrm_lo"`
+	RxPwrWarnHi   reading  `json:"rx_pwr_warn_hi"`
+	RxPwrWarnLo   reading  `json:"rx_pwr_warn_lo"`
+	TempFlag      []string `json:"temp_flag"`
+	VoltFlag      []string `json:"volt_flag"`
+	CurrentFlag   []string `json:"current_flag"`
+	TxPwrFlag     []string `json:"tx_pwr_flag"`
+	RxPwrFlag     []string `json:"rx_pwr_flag"`
+}
+
+func newTransceiverResult(b []byte) (*transceiverResult, error) {
+	xcvr := &transceiverResult{}
+	if err := decodeResult(b, xcvr); err != nil {
+		return nil, err
+	}
+	return xcvr, nil
+}
+
+// Inventory and digital optical monitoring of the transceivers from
+// "show interface transceiver details"
+func collectTransceivers(ms *metricSet, xcvr *transceiverResult) {
+	for _, t := range xcvr.Body.TableInterface {
+		for _, r := range t.RowInterface {
+			if r.Sfp != "present" {
+				continue
+			}
+			ms.Add(famXcvrInfo, 1, r.Interface, r.Type, r.Name, r.PartNum, r.Rev, r.SerialNum,
+				r.CiscoPartNumber, r.CiscoProductID)
+
+			// Transceivers without DOM have no lanes
+			for _, tl := range r.TableLane {
+				for _, l := range tl.RowLane {
+					collectTransceiverLane(ms, r.Interface, &l)
+				}
+			}
+		}
+	}
+}
+
+func collectTransceiverLane(ms *metricSet, intf string, r *transceiverLane) {
+	lane := fmt.Sprint(r.LaneNumber)
+
+	addReading(ms, famXcvrTemp, r.Temperature, intf, lane)
+	addThresholds(ms, famXcvrTempThr, intf, lane,
+		r.TempAlrmHi, r.TempAlrmLo, r.TempWarnHi, r.TempWarnLo)
+	addFlag(ms, famXcvrTempFlag, r.Temperature, r.TempFlag, intf, lane)
+
+	addReading(ms, famXcvrVolt, r.Voltage, intf, lane)
+	addThresholds(ms, famXcvrVoltThr, intf, lane,
+		r.VoltAlrmHi, r.VoltAlrmLo, r.VoltWarnHi, r.VoltWarnLo)
+	addFlag(ms, famXcvrVoltFlag, r.Voltage, r.VoltFlag, intf, lane)
+
+	// The switch reports the current in milliamperes
+	addReading(ms, famXcvrCurrent, r.Current.fromMilli(), intf, lane)
+	addThresholds(ms, famXcvrCurrentThr, intf, lane,
+		r.CurrentAlrmHi.fromMilli(), r.CurrentAlrmLo.fromMilli(), r.CurrentWarnHi.fromMilli(), r.CurrentWarnLo.fromMilli())
+	addFlag(ms, famXcvrCurrentFlag, r.Current, r.CurrentFlag, intf, lane)
+
+	addReading(ms, famXcvrTxPwr, r.TxPwr, intf, lane)
+	addThresholds(ms, famXcvrTxPwrThr, intf, lane,
+		r.TxPwrAlrmHi, r.TxPwrAlrmLo, r.TxPwrWarnHi, r.TxPwrWarnLo)
+	addFlag(ms, famXcvrTxPwrFlag, r.TxPwr, r.TxPwrFlag, intf, lane)
+
+	addReading(ms, famXcvrRxPwr, r.RxPwr, intf, lane)
+	addThresholds(ms, famXcvrRxPwrThr, intf, lane,
+		r.RxPwrAlrmHi, r.RxPwrAlrmLo, r.RxPwrWarnHi, r.RxPwrWarnLo)
+	addFlag(ms, famXcvrRxPwrFlag, r.RxPwr, r.RxPwrFlag, intf, lane)
+}
+
+// Add a reading, leaving out the ones the switch does not have, like the
+// power of a lane which is shut down
+func addReading(ms *metricSet, f *metricFamily, r reading, labelValues ...string) {
+	if r.valid {
+		ms.Add(f, r.value, labelValues...)
+	}
+}
+
+// Add the four thresholds of a reading
+func addThresholds(ms *metricSet, f *metricFamily, intf, lane string,
+	alarmHi, alarmLo, warnHi, warnLo reading) {
+	addReading(ms, f, alarmHi, intf, lane, "alarm_high")
+	addReading(ms, f, alarmLo, intf, lane, "alarm_low")
+	addReading(ms, f, warnHi, intf, lane, "warning_high")
+	addReading(ms, f, warnLo, intf, lane, "warning_low")
+}
+
+// Add the threshold flag of a reading, which says nothing without the reading
+func addFlag(ms *metricSet, f *metricFamily, r reading, flags []string, intf, lane string) {
+	if !r.valid {
+		return
+	}
+	flag := transceiverFlag(flags)
+	ms.AddState(f, okState(flag), flag, intf, lane)
+}
+
+// Convert a reading with two decimals from milli units to base units,
+// rounding off the noise of the division
+func fromMilli(v float64) float64 {
+	return math.Round(v*1e5) / 1e8
+}
+
+// The same for a reading of the switch
+func (r reading) fromMilli() reading {
+	r.value = fromMilli(r.value)
+	return r
+}
+
+// The switch marks a reading with "++" or "--" past an alarm threshold and
+// with "+" or "-" past a warning threshold
+func transceiverFlag(flags []string) string {
+	switch strings.TrimSpace(strings.Join(flags, "")) {
+	case "++":
+		return "high-alarm"
+	case "+":
+		return "high-warning"
+	case "-":
+		return "low-warning"
+	case "--":
+		return "low-alarm"
+	}
+	return "ok"
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCollectTransceivers(t *testing.T) {
+	dat, err := ioutil.ReadFile("testdata/show_interface_transceiver_details.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xcvr, err := newTransceiverResult(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := newMetricSet()
+	collectTransceivers(ms, xcvr)
+
+	got := string(ms.Format(formatText))
+	for _, want := range []string{
+		`cisco_transceiver_info{interface="Ethernet1/1",type="QSFP-100G-SR4",vendor="CISCO-AVAGO",partnum="AFBR-89CDDZ-CS1",rev="03",serial="AVF2231S0ZZ",cisco_part_number="10-3142-03",cisco_pid="QSFP-100G-SR4-S"} 1`,
+		`cisco_transceiver_temperature_celsius{interface="Ethernet1/1",lane="1"} 35.21`,
+		`cisco_transceiver_temperature_threshold_celsius{interface="Ethernet1/1",lane="1",threshold="alarm_low"} -5`,
+		`cisco_transceiver_temperature_flag{interface="Ethernet1/1",lane="1"} 1`,
+		`cisco_transceiver_voltage_volts{interface="Ethernet1/1",lane="2"} 3.28`,
+		`cisco_transceiver_voltage_flag{interface="Ethernet1/1",lane="2"} 1`,
+		`cisco_transceiver_current_amperes{interface="Ethernet1/1",lane="2"} 0.00961`,
+		`cisco_transceiver_current_flag{interface="Ethernet1/1",lane="2"} 0`,
+		`cisco_transceiver_tx_power_dbm{interface="Ethernet1/1",lane="1"} -0.71`,
+		`cisco_transceiver_rx_power_dbm{interface="Ethernet1/1",lane="1"} -19.52`,
+		`cisco_transceiver_rx_power_flag{interface="Ethernet1/1",lane="1"} 0`,
+		`cisco_transceiver_rx_power_flag{interface="Ethernet1/1",lane="2"} 1`,
+
+		// A port which is shut has no power or current, but still has the rest
+		`cisco_transceiver_temperature_celsius{interface="Ethernet1/2",lane="1"} 41.03`,
+		`cisco_transceiver_current_threshold_amperes{interface="Ethernet1/2",lane="1",threshold="alarm_high"} 0.07`,
+		`cisco_transceiver_tx_power_threshold_dbm{interface="Ethernet1/2",lane="1",threshold="warning_low"} -8.19`,
+
+		// A transceiver without DOM only has its inventory
+		`cisco_transceiver_info{interface="Ethernet1/4",type="10Gbase-SR",vendor="CISCO-AVAGO",partnum="SFBR-7700SDZ",rev="B4",serial="AGD1234ABCD",cisco_part_number="10-2415-03",cisco_pid="SFP-10G-SR"} 1`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("missing %s", want)
+		}
+	}
+	for _, missing := range []string{
+		`cisco_transceiver_current_amperes{interface="Ethernet1/2"`,
+		`cisco_transceiver_current_flag{interface="Ethernet1/2"`,
+		`cisco_transceiver_tx_power_dbm{interface="Ethernet1/2"`,
+		`cisco_transceiver_tx_power_flag{interface="Ethernet1/2"`,
+		`cisco_transceiver_rx_power_dbm{interface="Ethernet1/2"`,
+		`cisco_transceiver_rx_power_flag{interface="Ethernet1/2"`,
+		`interface="Ethernet1/3"`,
+		`cisco_transceiver_temperature_celsius{interface="Ethernet1/4"`,
+	} {
+		if strings.Contains(got, missing) {
+			t.Errorf("unexpected %s", missing)
+		}
+	}
+}
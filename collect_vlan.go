@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Metric families reported from "show vlan"
+var (
+	famVlanInfo = &metricFamily{Name: "cisco_vlan_info", Type: "info",
+		Help: "Name of the VLAN.", Labels: []string{"vlan_id", "name"}}
+	famVlanState = &metricFamily{Name: "cisco_vlan_state", Type: "stateset",
+		Help: "State of the VLAN, 1 when active.", Labels: []string{"vlan_id"},
+		States: []string{"active", "suspend", "shutdown"}}
+	famVlanPorts = &metricFamily{Name: "cisco_vlan_member_ports", Type: "gauge",
+		Help: "Number of ports which are members of the VLAN.", Labels: []string{"vlan_id"}}
+)
+
+// Reply to "show vlan".  The client finds the rows of the table by byte
+// offsets, which break on JSON without a space after the colons, and parses
+// the whole JSON-RPC object rather than the result.
+type vlanResult struct {
+	Body struct {
+		TableVlanbrief []struct {
+			RowVlanbrief []struct {
+				ID            string `json:"vlanshowbr-vlanid"`
+				Name          string `json:"vlanshowbr-vlanname"`
+				State         string `json:"vlanshowbr-vlanstate"`
+				ShutdownState string `json:"vlanshowbr-shutstate"`
+				// One or more comma separated lists of ports
+				Ports []string `json:"vlanshowplist-ifidx"`
+			} `json:"ROW_vlanbrief"`
+		} `json:"TABLE_vlanbrief"`
+	} `json:"body"`
+}
+
+func newVlanResult(b []byte) (*vlanResult, error) {
+	vlans := &vlanResult{}
+	if err := decodeResult(b, vlans); err != nil {
+		return nil, err
+	}
+	return vlans, nil
+}
+
+// VLANs and their member ports from "show vlan"
+func collectVlans(ms *metricSet, vlans *vlanResult) {
+	for _, t := range vlans.Body.TableVlanbrief {
+		for _, v := range t.RowVlanbrief {
+			ms.Add(famVlanInfo, 1, v.ID, v.Name)
+			// A VLAN which is shut down still reports itself as active
+			state := strings.ToLower(v.State)
+			if strings.EqualFold(v.ShutdownState, "shutdown") {
+				state = "shutdown"
+			}
+			ms.AddState(famVlanState, isState(state, "active"), state, v.ID)
+			ms.Add(famVlanPorts, float64(countPorts(strings.Split(strings.Join(v.Ports, ","), ","))), v.ID)
+		}
+	}
+}
+
+// Count the ports of a member list, where the switch folds neighbouring ports
+// into ranges like "Ethernet1/1-4"
+func countPorts(ports []string) (n int) {
+	for _, p := range ports {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n++
+		dash := strings.LastIndex(p, "-")
+		if dash < 0 {
+			continue
+		}
+		hi, err := strconv.Atoi(p[dash+1:])
+		if err != nil {
+			continue
+		}
+		start := strings.LastIndexFunc(p[:dash], func(r rune) bool { return r < '0' || r > '9' }) + 1
+		if lo, err := strconv.Atoi(p[start:dash]); err == nil && hi > lo {
+			n += hi - lo
+		}
+	}
+	return
+}
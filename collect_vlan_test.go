@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVlanCompactReply(t *testing.T) {
+	// A single row without spaces, with the ports as one list
+	vlans, err := newVlanResult([]byte(`{"body":{"TABLE_vlanbrief":{"ROW_vlanbrief":{"vlanshowbr-vlanid":"10",` +
+		`"vlanshowbr-vlanname":"servers","vlanshowbr-vlanstate":"active","vlanshowbr-shutstate":"noshutdown",` +
+		`"vlanshowplist-ifidx":"port-channel10,Ethernet1/1-4"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := newMetricSet()
+	collectVlans(ms, vlans)
+
+	want := "# HELP cisco_vlan_member_ports Number of ports which are members of the VLAN.\n" +
+		"# TYPE cisco_vlan_member_ports gauge\n" +
+		"cisco_vlan_member_ports{vlan_id=\"10\"} 5\n"
+	if got := string(ms.Format(formatText)); !strings.Contains(got, want) {
+		t.Errorf("output:\n%s\nwant it to contain:\n%s", got, want)
+	}
+}
+
+func TestCountPorts(t *testing.T) {
+	tests := []struct {
+		ports []string
+		want  int
+	}{
+		{nil, 0},
+		{[]string{""}, 0},
+		{[]string{"Ethernet1/1"}, 1},
+		{[]string{"Ethernet1/1-4", "Ethernet1/10"}, 5},
+		{[]string{"port-channel10", "port-channel20-21", "Ethernet1/5"}, 4},
+		{[]string{"Ethernet1/1/1-4"}, 4},
+	}
+	for _, tt := range tests {
+		if got := countPorts(tt.ports); got != tt.want {
+			t.Errorf("countPorts(%q) = %d, want %d", tt.ports, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Metric families reported from "show vpc"
+var (
+	famVpcPeerStatus = &metricFamily{Name: "cisco_vpc_peer_status", Type: "stateset",
+		Help: "Status of the vPC peer, 1 when ok.", Labels: []string{"domain"},
+		States: []string{"peer-ok", "peer-not-alive", "peer-link-down", "peer-not-configured"}}
+	famVpcKeepalive = &metricFamily{Name: "cisco_vpc_peer_keepalive_status", Type: "stateset",
+		Help: "Status of the vPC peer keepalive link, 1 when the peer is alive.", Labels: []string{"domain"},
+		States: []string{"peer-alive", "peer-not-alive", "peer-unknown", "suspended", "not-configured"}}
+	famVpcConsistency = &metricFamily{Name: "cisco_vpc_peer_consistency", Type: "stateset",
+		Help: "Result of the vPC consistency check with the peer, 1 when consistent.", Labels: []string{"domain", "type"},
+		States: []string{"consistent", "inconsistent", "not-applicable"}}
+	famVpcRole = &metricFamily{Name: "cisco_vpc_role", Type: "stateset",
+		Help: "vPC role of the switch, 1 when it is operationally primary.", Labels: []string{"domain"},
+		States: []string{"primary", "secondary", "primary, operational secondary",
+			"secondary, operational primary", "none-established"}}
+	famVpcCount = &metricFamily{Name: "cisco_vpc_count", Type: "gauge",
+		Help: "Number of vPCs configured on the switch.", Labels: []string{"domain"}}
+
+	famVpcPortState = &metricFamily{Name: "cisco_vpc_port_state", Type: "gauge",
+		Help: "State of the vPC port channel, 1 when up.", Labels: []string{"vpc", "interface"}}
+	famVpcPortConsistency = &metricFamily{Name: "cisco_vpc_port_consistency", Type: "stateset",
+		Help: "Result of the consistency check of the vPC, 1 when consistent.", Labels: []string{"vpc", "interface"},
+		States: []string{"consistent", "inconsistent", "not-applicable"}}
+	famVpcPeerlinkState = &metricFamily{Name: "cisco_vpc_peerlink_port_state", Type: "gauge",
+		Help: "State of the vPC peer-link port channel, 1 when up.", Labels: []string{"peerlink", "interface"}}
+)
+
+// vPC domain, peer and port health from "show vpc"
+func collectVpc(ms *metricSet, vpc *client.ShowVpcResponseResult) {
+	b := &vpc.Body
+	// Switches without vPC configured have an empty body
+	if b.VpcDomainID == "" && len(b.TableVpc) == 0 {
+		return
+	}
+
+	ms.AddState(famVpcPeerStatus, isState(b.VpcPeerStatus, "peer-ok"), b.VpcPeerStatus, b.VpcDomainID)
+	ms.AddState(famVpcKeepalive, isState(b.VpcPeerKeepaliveStatus, "peer-alive"), b.VpcPeerKeepaliveStatus, b.VpcDomainID)
+	ms.AddState(famVpcConsistency, isState(b.VpcPeerConsistency, "consistent"), b.VpcPeerConsistency, b.VpcDomainID, "global")
+	if b.VpcType2Consistency != "" {
+		ms.AddState(famVpcConsistency, isState(b.VpcType2Consistency, "consistent"), b.VpcType2Consistency, b.VpcDomainID, "type-2")
+	}
+	primary := isState(b.VpcRole, "primary") + isState(b.VpcRole, "secondary, operational primary")
+	ms.AddState(famVpcRole, primary, b.VpcRole, b.VpcDomainID)
+	ms.Add(famVpcCount, float64(b.NumOfVpcs), b.VpcDomainID)
+
+	for _, t := range b.TableVpc {
+		for _, r := range t.RowVpc {
+			id := fmt.Sprint(r.VpcID)
+			intf := longInterfaceName(r.VpcIfindex)
+			ms.Add(famVpcPortState, portUp(r.VpcPortState), id, intf)
+			ms.AddState(famVpcPortConsistency, isState(r.VpcConsistency, "consistent"), r.VpcConsistency, id, intf)
+		}
+	}
+	for _, t := range b.TablePeerlink {
+		for _, r := range t.RowPeerlink {
+			ms.Add(famVpcPeerlinkState, portUp(r.PeerLinkPortState), r.PeerLinkID, longInterfaceName(r.PeerlinkIfindex))
+		}
+	}
+}
+
+// vPC port states come as "1" for up and "0" for down
+func portUp(s string) float64 {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "up":
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+func TestCollectVpcInterfaceNames(t *testing.T) {
+	// The ports come with their short names, the other collectors use the long ones
+	vpc, err := client.NewShowVpcResultFromBytes([]byte(`{"body": {"vpc-domain-id": "10", "vpc-peer-status": "peer-ok",
+		"vpc-peer-keepalive-status": "peer-alive", "vpc-peer-consistency": "consistent", "vpc-role": "primary",
+		"num-of-vpcs": "1",
+		"TABLE_peerlink": {"ROW_peerlink": {"peer-link-id": "1", "peerlink-ifindex": "Po1", "peer-link-port-state": "1"}},
+		"TABLE_vpc": {"ROW_vpc": {"vpc-id": "10", "vpc-ifindex": "Po10", "vpc-port-state": "1", "vpc-consistency": "consistent"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := newMetricSet()
+	collectVpc(ms, vpc)
+
+	got := string(ms.Format(formatText))
+	for _, want := range []string{
+		`cisco_vpc_port_state{vpc="10",interface="port-channel10"} 1`,
+		`cisco_vpc_port_consistency{vpc="10",interface="port-channel10"} 1`,
+		`cisco_vpc_peerlink_port_state{peerlink="1",interface="port-channel1"} 1`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, got)
+		}
+	}
+}
@@ -2,49 +2,101 @@ package main
 
 import (
 	"log"
+	"sync/atomic"
 	"time"
 )
 
+// The config in use.  A reload swaps in a new one as a whole, so whatever runs
+// alongside takes a snapshot with getConfig and keeps to it.
+var currentConfig atomic.Value
+
+// Snapshot of the config in use
+func getConfig() configStruct {
+	config, _ := currentConfig.Load().(configStruct)
+	return config
+}
+
+func setConfig(config configStruct) {
+	currentConfig.Store(config)
+}
+
 // Handle the loading and parsing the timing for the config
 func readAndParseConfig() {
-	var err error
-
 	// Read Config
 	log.Println("Loading the configuration file.")
-	config, err = readConfig(*config_file)
-	printError(err, "Error reading or parsing config file:", *config_file, "error:", err)
+	config, err := readConfig(*config_file)
+	if err != nil {
+		// Keep going with the config which was loaded before, if any, a bad
+		// one is only fatal on startup
+		if currentConfig.Load() == nil {
+			log.Fatal("Error reading or parsing config file: ", *config_file, " error: ", err)
+		}
+		printError(err, "Error reading or parsing config file:", *config_file, "keeping the current config")
+		return
+	}
 
-	// Parse the interval
+	// Parse the interval, readConfig made sure it parses
+	var queryInterval time.Duration
 	if len(config.Interval) > 0 {
-		queryInterval, err = time.ParseDuration(config.Interval)
-		printError(err, "Parsing interval error:", err)
+		queryInterval, _ = time.ParseDuration(config.Interval)
 	} else {
 		// One time shot deal
-		oneTime = true
+		config.oneTime = true
 	}
 
 	//Count the total number of hosts to query
-	host_count = 0
 	for _, qryConf := range config.Nxapi {
 		for range qryConf.Host {
-			host_count++
+			config.hostCount++
 		}
 	}
-	log.Println("Found", host_count, "hosts in the config.")
+	log.Println("Found", config.hostCount, "hosts in the config.")
 
-	if host_count == 0 {
+	// Hosts are optional when Prometheus sends them to the /probe endpoint
+	if config.hostCount == 0 && config.Listen == "" {
 		log.Fatal("No hosts found, please add hosts for querying")
 	}
 
 	// Figure out the timing
-	if !oneTime {
-		queryStep = time.Duration(int64(queryInterval) / int64(host_count))
+	if !config.oneTime && config.hostCount > 0 {
+		config.queryStep = time.Duration(int64(queryInterval) / int64(config.hostCount))
 	}
+
+	// Swap in the new config along with its timing
+	oldConfig := getConfig()
+	setConfig(config)
+	removeStaleHosts(oldConfig, config)
 }
 
-// Print error to the screen
+// Clean up after the hosts which were dropped from the config on a reload
+func removeStaleHosts(oldConfig, newConfig configStruct) {
+	current := make(map[string]bool)
+	for _, qryConf := range newConfig.Nxapi {
+		for _, host := range qryConf.Host {
+			current[host] = true
+			current[pushgatewayURL(newConfig.Push, newConfig.Job, host, qryConf)] = true
+		}
+	}
+
+	for _, qryConf := range oldConfig.Nxapi {
+		for _, host := range qryConf.Host {
+			if !current[host] {
+				dropHostMetrics(host)
+			}
+
+			// A host whose grouping changed leaves its old group behind too
+			if oldConfig.PushMode == "pushgateway" && oldConfig.Push != "" &&
+				!current[pushgatewayURL(oldConfig.Push, oldConfig.Job, host, qryConf)] {
+				go DeleteFromPushgateway(oldConfig.Push, oldConfig.Job, host, qryConf)
+			}
+		}
+	}
+}
+
+// Print error to the screen, a command which was turned off or failed has
+// nothing to parse and is not an error here
 func printRespErr(err error, t string, dat []byte) {
-	if err != nil {
+	if err != nil && dat != nil {
 		log.Println(t, "=", string(dat))
 		log.Println("err=", err)
 	}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Lock so the hosts queried in parallel append whole batches to the file
+var influxFileLock sync.Mutex
+
+// Send the metrics of a host to InfluxDB as line protocol, either to a v2
+// /api/v2/write endpoint or appended to a file
+func WriteInflux(host string, ms *metricSet) (err error) {
+	dat := encodeLineProtocol(host, ms)
+
+	config := getConfig()
+	if config.InfluxDB.File != "" {
+		influxFileLock.Lock()
+		defer influxFileLock.Unlock()
+		var f *os.File
+		f, err = os.OpenFile(config.InfluxDB.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = f.Write(dat)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			log.Println("Error writing line protocol for host", host, "err", err)
+		}
+		return
+	}
+
+	q := url.Values{}
+	q.Set("org", config.InfluxDB.Org)
+	q.Set("bucket", config.InfluxDB.Bucket)
+	q.Set("precision", "ns")
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token := readAtFile(config.InfluxDB.Token); token != "" {
+		header.Set("Authorization", "Token "+token)
+	}
+
+	err = sendWithRetry("POST", strings.TrimSuffix(config.Push, "/")+"/api/v2/write?"+q.Encode(), dat, header)
+	if err != nil {
+		log.Println("Error in influxdb write for host", host, "err", err)
+	} else {
+		log.Println("...pushed")
+	}
+	return
+}
+
+// Encode the metrics as line protocol, a measurement per family with the
+// labels as tags and the sample in a value field:
+//
+//	cisco_bgp_state,host=sw1,neighborID=19.0.101.1 value=1,state="Established" 1527099972000000000
+func encodeLineProtocol(host string, ms *metricSet) []byte {
+	var buf bytes.Buffer
+	ts := strconv.FormatInt(ms.Time.UnixNano(), 10)
+	for _, f := range ms.families {
+		measurement := sanitizeName(f.Name)
+		for _, s := range ms.samples[f] {
+			// InfluxDB has no way to store these
+			if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+				continue
+			}
+
+			buf.WriteString(measurement)
+			buf.WriteString(",host=" + tagEscaper.Replace(host))
+			for _, l := range f.labelsOf(s) {
+				buf.WriteString("," + sanitizeLabelName(l.name) + "=" + tagEscaper.Replace(l.value))
+			}
+			buf.WriteString(" value=" + formatValue(s.Value))
+			if f.Type == "stateset" {
+				buf.WriteString(`,state="` + fieldEscaper.Replace(s.State) + `"`)
+			}
+			buf.WriteString(" " + ts + "\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// Line protocol escaping, newlines cannot be escaped so they become spaces
+var tagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `, `\`, `\\`)
+var fieldEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLineProtocolEscaping(t *testing.T) {
+	tests := []struct {
+		in         string
+		tag, field string
+	}{
+		{"Ethernet1/1", "Ethernet1/1", "Ethernet1/1"},
+		{"Shut (Admin)", `Shut\ (Admin)`, "Shut (Admin)"},
+		{"a,b=c", `a\,b\=c`, "a,b=c"},
+		{"two\nlines", `two\ lines`, "two\nlines"},
+		{`C:\x`, `C:\\x`, `C:\\x`},
+		{`say "hi"`, `say\ "hi"`, `say \"hi\"`},
+	}
+	for _, tt := range tests {
+		if got := tagEscaper.Replace(tt.in); got != tt.tag {
+			t.Errorf("tag %q = %q, want %q", tt.in, got, tt.tag)
+		}
+		if got := fieldEscaper.Replace(tt.in); got != tt.field {
+			t.Errorf("field %q = %q, want %q", tt.in, got, tt.field)
+		}
+	}
+}
+
+func TestEncodeLineProtocol(t *testing.T) {
+	ms := newMetricSet()
+	ms.Time = time.Unix(1527099972, 0)
+	ms.Add(&metricFamily{Name: "cisco_test_errors", Type: "counter", Labels: []string{"intf", "descr"}},
+		3, "Ethernet1/1", "uplink, core=1")
+	ms.Add(&metricFamily{Name: "cisco_test_temp", Type: "gauge", Labels: []string{"sensor"}}, math.NaN(), "cpu")
+	ms.AddState(&metricFamily{Name: "cisco_test_state", Type: "stateset", Labels: []string{"neighborID", "vrf"},
+		States: []string{"up", "down"}}, 1, `up "now"`, "19.0.101.1", "")
+
+	want := `cisco_test_errors,host=sw\ 1,intf=Ethernet1/1,descr=uplink\,\ core\=1 value=3 1527099972000000000
+cisco_test_state,host=sw\ 1,neighborID=19.0.101.1 value=1,state="up \"now\"" 1527099972000000000
+`
+	if got := string(encodeLineProtocol("sw 1", ms)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
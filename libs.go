@@ -1,29 +1,104 @@
 package main
 
 import (
+	"fmt"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"log"
+	"strings"
+	"time"
 )
 
 type configStruct struct {
 	Version  int     `yaml:"version"`
 	Push     string  `yaml:"push"`
+	PushMode string  `yaml:"push_mode"`
+	Job      string  `yaml:"job"`
+	Listen   string  `yaml:"listen"`
+	Textfile string  `yaml:"textfile_dir"`
 	Interval string  `yaml:"interval"`
 	Nxapi    []Nxapi `yaml:"nxapi"`
+
+	// Block to probe targets with which are not listed in any block
+	DefaultModule string `yaml:"default_module"`
+
+	InfluxDB InfluxDB `yaml:"influxdb"`
+	OTLP     OTLP     `yaml:"otlp"`
+
+	// Push without checking the certificate of the receiver
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+
+	// Timing worked out from the interval and the hosts on loading
+	queryStep time.Duration
+	hostCount int
+	oneTime   bool
+}
+
+// InfluxDB
+type InfluxDB struct {
+	Org    string `yaml:"org"`
+	Bucket string `yaml:"bucket"`
+	Token  string `yaml:"token"`
+	File   string `yaml:"file"`
+}
+
+// OTLP
+type OTLP struct {
+	Encoding string            `yaml:"encoding"`
+	Headers  map[string]string `yaml:"headers"`
 }
 
 // Nxapi
 type Nxapi struct {
+	Name     string   `yaml:"name"`
 	User     string   `yaml:"user"`
 	Password string   `yaml:"password"`
 	Host     []string `yaml:"host"`
 	Port     int      `yaml:"port"`
 	Protocol string   `yaml:"protocol"`
+
+	// Extra grouping labels for a Pushgateway
+	Grouping map[string]string `yaml:"grouping"`
+
+	// Fields of "show interface" to export, all of them when empty
+	InterfaceCounters []string `yaml:"interface_counters"`
+
+	// Export every port-security address rather than just the counts
+	PortSecurityEntries bool `yaml:"port_security_entries"`
+
+	// Export every ARP entry rather than just the counts
+	ArpEntries bool `yaml:"arp_entries"`
+
+	// Collectors to turn off, by name, with false
+	Collectors map[string]bool `yaml:"collectors"`
 }
 
 var version = ""
 
+// Find the config block for a probe, by module name or else by listed host.
+// A host which is not listed only falls back to the default_module block, so
+// the credentials are never sent to whatever target the caller names.
+func findNxapi(config configStruct, module, host string) (qryConf Nxapi, ok bool) {
+	if module == "" {
+		for _, qryConf = range config.Nxapi {
+			for _, h := range qryConf.Host {
+				if h == host {
+					return qryConf, true
+				}
+			}
+		}
+		if module = config.DefaultModule; module == "" {
+			return Nxapi{}, false
+		}
+	}
+	for _, qryConf = range config.Nxapi {
+		if qryConf.Name == module {
+			return qryConf, true
+		}
+	}
+	return Nxapi{}, false
+}
+
 func stateSwitch(s string) (state int) {
 	switch s {
 	case "unknown":
@@ -38,6 +113,40 @@ func stateSwitch(s string) (state int) {
 	return
 }
 
+// A count of rows per label value.  The values are kept in the order they are
+// first seen, rather than the random order of a map, so the series come out in
+// the order the switch lists its rows.
+type rowCount struct {
+	values []string
+	n      map[string]int
+}
+
+func (c *rowCount) Add(value string) {
+	if c.n == nil {
+		c.n = make(map[string]int)
+	}
+	if _, ok := c.n[value]; !ok {
+		c.values = append(c.values, value)
+	}
+	c.n[value]++
+}
+
+// Add a sample of f with each value and its count, after the labels given
+func (c *rowCount) AddTo(ms *metricSet, f *metricFamily, labelValues ...string) {
+	for _, value := range c.values {
+		ms.Add(f, float64(c.n[value]), append(labelValues[:len(labelValues):len(labelValues)], value)...)
+	}
+}
+
+// Read a value from a file when it starts with an @ sign
+func readAtFile(s string) string {
+	if len(s) > 0 && s[0] == '@' {
+		dat, _ := ioutil.ReadFile(s[1:])
+		return strings.TrimSpace(string(dat))
+	}
+	return s
+}
+
 func printError(err error, str ...interface{}) {
 	if err == nil {
 		return
@@ -61,6 +170,10 @@ func readConfig(config_file string) (config configStruct, err error) {
 		return
 	}
 
+	if config.Job == "" {
+		config.Job = "cisco"
+	}
+
 	//err = yaml.Unmarshal([]byte(data), &conf)
 	for i, qryConf := range config.Nxapi {
 		// Set some defaults
@@ -71,5 +184,37 @@ func readConfig(config_file string) (config configStruct, err error) {
 			config.Nxapi[i].Protocol = "https"
 		}
 	}
+
+	err = checkConfig(config)
 	return
 }
+
+// Check the settings which would otherwise only fail once they are used
+func checkConfig(config configStruct) error {
+	if config.Interval != "" {
+		if _, err := time.ParseDuration(config.Interval); err != nil {
+			return fmt.Errorf("interval: %s", err)
+		}
+	}
+	for _, qryConf := range config.Nxapi {
+		if err := checkIntfCounters(qryConf.InterfaceCounters); err != nil {
+			return fmt.Errorf("nxapi block %q: %s", qryConf.Name, err)
+		}
+		if err := checkCollectors(qryConf.Collectors); err != nil {
+			return fmt.Errorf("nxapi block %q: %s", qryConf.Name, err)
+		}
+	}
+	if config.DefaultModule != "" && !hasModule(config, config.DefaultModule) {
+		return fmt.Errorf("default_module %q names no nxapi block", config.DefaultModule)
+	}
+	return nil
+}
+
+func hasModule(config configStruct, name string) bool {
+	for _, qryConf := range config.Nxapi {
+		if qryConf.Name == name {
+			return true
+		}
+	}
+	return false
+}
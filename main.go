@@ -1,16 +1,16 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"github.com/pschou/go-cisco-nx-api/pkg/client"
 	"github.com/pschou/go-params"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -19,12 +19,9 @@ type res struct {
 	Result []byte
 }
 
-var queryStep, queryInterval time.Duration
-var oneTime bool
-var host_count int
 var nextRun time.Time
-var config configStruct
 var config_file *string
+var queryWait sync.WaitGroup
 
 func main() {
 	// Define and parse arguments
@@ -44,10 +41,16 @@ func main() {
 		}
 	}()
 
+	// Serve the latest metrics for scraping when a listen address is set
+	if listen := getConfig().Listen; listen != "" {
+		go startListener(listen)
+	}
+
 	nextRun = time.Now()
 
-	// Loop for query interval
+	// Loop for query interval, picking up a reloaded config each time around
 	for {
+		config := getConfig()
 
 		// Loop over config blocks
 		for _, qryConf := range config.Nxapi {
@@ -56,40 +59,233 @@ func main() {
 			// Loop over hosts in the config
 			for _, host := range qryConf.Host {
 
-				go queryHost(host, qryConf)
+				queryWait.Add(1)
+				go func(host string, qryConf Nxapi) {
+					defer queryWait.Done()
+					queryHost(host, qryConf)
+				}(host, qryConf)
 
 				// Add delay for next query
-				nextRun = nextRun.Add(queryStep)
+				nextRun = nextRun.Add(config.queryStep)
 				sleep_time := nextRun.Sub(time.Now())
-				for sleep_time > queryStep {
-					sleep_time -= queryStep
+				for sleep_time > config.queryStep {
+					sleep_time -= config.queryStep
 				}
 				time.Sleep(sleep_time)
 			}
 		}
-		if oneTime == true {
+		if config.oneTime == true {
+			// Let the queries of a one time shot finish before exiting
+			queryWait.Wait()
 			break
 		}
+
+		// With no hosts to poll, wait for a config reload
+		if config.hostCount == 0 {
+			time.Sleep(time.Second)
+			nextRun = time.Now()
+		}
+	}
+
+	// Keep serving the collected metrics after a one time shot
+	if getConfig().Listen != "" {
+		select {}
 	}
 }
 
-// The bulk of the querying is done here
+// Query a host on the schedule and send the metrics where they need to go
 func queryHost(host string, qryConf Nxapi) {
-	// Create buffer for output to send or to display
-	var buf bytes.Buffer
+	ms, err := collectHost(context.Background(), host, qryConf)
+	if err != nil {
+		log.Println("Error in call to API for host", host, "err", err)
+		return
+	}
+	config := getConfig()
 
-	log.Println("Querying host", host)
-	cli := client.NewClient()
-	cli.SetHost(host)
-	cli.SetUsername(qryConf.User)
+	// Keep the latest result around for the HTTP listener
+	if config.Listen != "" {
+		setHostMetrics(host, ms)
+	}
+
+	// Write the result for the node_exporter textfile collector instead of pushing
+	if config.Textfile != "" {
+		WriteTextfile(config.Textfile, host, ms)
+		return
+	}
+
+	// The InfluxDB file output needs no push URL
+	influxFile := config.PushMode == "influxdb" && config.InfluxDB.File != ""
 
-	// Look at the password in the config file
-	password := qryConf.Password
-	// If it starts with an @ sign, consider it a file
-	if len(password) > 0 && password[0] == '@' {
-		dat, _ := ioutil.ReadFile(password[1:])
-		password = strings.TrimSpace(string(dat))
+	if config.Push == "" && !influxFile {
+		// Print out the result when there is nowhere else for it to go
+		if config.Listen == "" {
+			fmt.Printf("metrics:\n%s", ms.Bytes())
+		}
+		return
+	}
+
+	switch config.PushMode {
+	case "", "collector":
+		// Send the result to Prometheus Collector
+		UploadToCollector(strings.TrimSuffix(config.Push, "/")+"/host/"+host, ms)
+	case "remote_write":
+		// Send the result straight to a Prometheus compatible receiver
+		RemoteWrite(config.Push, host, ms)
+	case "pushgateway":
+		// Replace the group of the host on a Pushgateway
+		UploadToPushgateway(config.Push, config.Job, host, qryConf, ms)
+	case "influxdb":
+		// Write the result as InfluxDB line protocol
+		WriteInflux(host, ms)
+	case "otlp":
+		// Send the result to an OpenTelemetry collector
+		ExportOTLP(config.Push, host, ms)
+	default:
+		log.Println("Unknown push_mode", config.PushMode, "for host", host)
+	}
+}
+
+// Metric families reported from the show commands in collectHost
+var (
+	famInfo = &metricFamily{Name: "cisco_info", Type: "info",
+		Help:   "Version and hardware information of the device.",
+		Labels: []string{"biosVer", "sysVer", "boardID", "chassisID"}}
+	famResetTime = &metricFamily{Name: "cisco_reset_time", Type: "gauge",
+		Help:   "Unix time of the last reset of the device.",
+		Labels: []string{"rr_reason", "rr_service", "rr_sysVer"}}
+	famLastScrape = &metricFamily{Name: "cisco_last_scrape_timestamp_seconds", Type: "gauge",
+		Help: "Unix time when the device answered the last query."}
+	famUptime = &metricFamily{Name: "cisco_uptime_seconds", Type: "gauge",
+		Help: "Kernel uptime of the device in seconds."}
+
+	bgpLabels      = []string{"neighborID", "remoteAS", "localAS", "routerID"}
+	famBgpLastFlap = &metricFamily{Name: "cisco_bgp_lastflap_seconds", Type: "gauge",
+		Help: "Seconds since the last flap of the BGP session.", Labels: bgpLabels}
+	famBgpState = &metricFamily{Name: "cisco_bgp_state", Type: "stateset",
+		Help: "State of the BGP session, 1 when established.", Labels: bgpLabels,
+		States: []string{"Idle", "Connect", "Active", "OpenSent", "OpenConfirm", "Established"}}
+	famBgpConnDrop = &metricFamily{Name: "cisco_bgp_conndrop_count", Type: "counter",
+		Help: "Number of times the BGP session connection has dropped.", Labels: bgpLabels}
+
+	routeLabels    = []string{"clientName", "ifName", "ipPrefix"}
+	famRouteUptime = &metricFamily{Name: "cisco_ip_route_uptime_seconds", Type: "gauge",
+		Help: "Seconds since the route was installed.", Labels: routeLabels}
+	famRouteMcastHops = &metricFamily{Name: "cisco_ip_route_mcast_hops", Type: "gauge",
+		Help: "Number of multicast next hops of the route.", Labels: routeLabels}
+	famRouteUcastHops = &metricFamily{Name: "cisco_ip_route_ucast_hops", Type: "gauge",
+		Help: "Number of unicast next hops of the route.", Labels: routeLabels}
+	famRoutePref = &metricFamily{Name: "cisco_ip_route_pref", Type: "gauge",
+		Help: "Administrative preference of the route.", Labels: routeLabels}
+	famRouteMetric = &metricFamily{Name: "cisco_ip_route_metric", Type: "gauge",
+		Help: "Metric of the route.", Labels: routeLabels}
+
+	famIntfSpeed = &metricFamily{Name: "cisco_interface_speed_bits", Type: "gauge",
+		Help:   "Speed of the interface in bits per second.",
+		Labels: []string{"interface", "state", "vlan", "type", "autoSpeed"}}
+
+	intfLabels  = []string{"interface", "mac"}
+	famIntfInfo = &metricFamily{Name: "cisco_interface_info", Type: "info",
+		Help:   "Description and settings of the interface.",
+		Labels: []string{"interface", "mac", "desc", "eth_autoneg"}}
+	intfStates   = []string{"unknown", "down", "up", "link-up"}
+	famIntfState = &metricFamily{Name: "cisco_interface_state", Type: "stateset",
+		Help: "Operational state of the interface, -1 unknown, 0 down, 1 up, 2 link-up.", Labels: intfLabels,
+		States: intfStates}
+	famIntfAdminState = &metricFamily{Name: "cisco_interface_admin_state", Type: "stateset",
+		Help: "Administrative state of the interface, -1 unknown, 0 down, 1 up, 2 link-up.", Labels: intfLabels,
+		States: intfStates}
+	famIntfBW = &metricFamily{Name: "cisco_interface_bw_bits", Type: "gauge",
+		Help:   "Bandwidth of the interface in bits per second.",
+		Labels: []string{"interface", "mac", "stream"}}
+
+	famVdcInPkts = &metricFamily{Name: "cisco_interface_vdc_lvl_in_pkts", Type: "counter",
+		Help: "Packets received on the interface at the VDC level.", Labels: intfLabels}
+	famVdcInBytes = &metricFamily{Name: "cisco_interface_vdc_lvl_in_bytes", Type: "counter",
+		Help: "Bytes received on the interface at the VDC level.", Labels: intfLabels}
+	famVdcInUCast = &metricFamily{Name: "cisco_interface_vdc_lvl_in_ucast_pkts", Type: "counter",
+		Help: "Unicast packets received on the interface at the VDC level.", Labels: intfLabels}
+	famVdcInMCast = &metricFamily{Name: "cisco_interface_vdc_lvl_in_mcast_pkts", Type: "counter",
+		Help: "Multicast packets received on the interface at the VDC level.", Labels: intfLabels}
+	famVdcInBCast = &metricFamily{Name: "cisco_interface_vdc_lvl_in_bcast_pkts", Type: "counter",
+		Help: "Broadcast packets received on the interface at the VDC level.", Labels: intfLabels}
+	famVdcOutPkts = &metricFamily{Name: "cisco_interface_vdc_lvl_out_pkts", Type: "counter",
+		Help: "Packets sent on the interface at the VDC level.", Labels: intfLabels}
+	famVdcOutBytes = &metricFamily{Name: "cisco_interface_vdc_lvl_out_bytes", Type: "counter",
+		Help: "Bytes sent on the interface at the VDC level.", Labels: intfLabels}
+	famVdcOutUCast = &metricFamily{Name: "cisco_interface_vdc_lvl_out_ucast_pkts", Type: "counter",
+		Help: "Unicast packets sent on the interface at the VDC level.", Labels: intfLabels}
+	famVdcOutMCast = &metricFamily{Name: "cisco_interface_vdc_lvl_out_mcast_pkts", Type: "counter",
+		Help: "Multicast packets sent on the interface at the VDC level.", Labels: intfLabels}
+	famVdcOutBCast = &metricFamily{Name: "cisco_interface_vdc_lvl_out_bcast_pkts", Type: "counter",
+		Help: "Broadcast packets sent on the interface at the VDC level.", Labels: intfLabels}
+
+	famIsisAdjTransitions = &metricFamily{Name: "cisco_isis_adj_transitions", Type: "counter",
+		Help:   "Number of state transitions of the ISIS adjacency.",
+		Labels: []string{"intfOut", "iPAddrOut", "iP6AddrOut"}}
+)
+
+// The commands sent to the devices, by the name of the collector which reads
+// the reply.  Each of them can be turned off with collectors in an nxapi block.
+var collectorCommands = []struct{ name, cmd string }{
+	{"version", "show version"},
+	{"bgp", "show bgp session"},
+	{"route", "show ip route"},
+	{"arp", "show ip arp detail vrf all"},
+	{"interface_status", "show interface status"},
+	{"interface_quick", "show interface quick"},
+	{"isis", "show isis adj detail"},
+	{"transceiver", "show interface transceiver details"},
+	{"environment", "show environment"},
+	{"resources", "show system resources"},
+	{"vpc", "show vpc"},
+	{"hsrp", "show hsrp"},
+	{"module", "show module"},
+	{"interface_errors", "show interface counters errors"},
+	{"interface", "show interface"},
+	{"cdp", "show cdp neighbors"},
+	{"ntp", "show ntp peer-status"},
+	{"eigrp", "show ip eigrp neighbors vrf all"},
+	{"port_security", "show port-security address"},
+	{"vlan", "show vlan"},
+	{"ospf", "show ip ospf neighbors detail vrf all"},
+}
+
+// Collectors are on unless the nxapi block turns them off
+func collectorEnabled(qryConf Nxapi, name string) bool {
+	on, ok := qryConf.Collectors[name]
+	return on || !ok
+}
+
+// Check the names in collectors
+func checkCollectors(collectors map[string]bool) error {
+	known := make(map[string]bool)
+	for _, c := range collectorCommands {
+		known[c.name] = true
+	}
+	for name := range collectors {
+		if !known[name] {
+			return fmt.Errorf("unknown collector %q", name)
+		}
+	}
+	for name := range known {
+		if on, ok := collectors[name]; on || !ok {
+			return nil
+		}
 	}
+	return fmt.Errorf("all collectors are turned off")
+}
+
+// The bulk of the querying is done here, the query to the device is dropped
+// when ctx is done
+func collectHost(ctx context.Context, host string, qryConf Nxapi) (*metricSet, error) {
+	// Create the set of metrics to send or to display
+	ms := newMetricSet()
+
+	log.Println("Querying host", host)
+
+	// Look at the password in the config file, if it starts with an @ sign,
+	// consider it a file
+	password := readAtFile(qryConf.Password)
 	// If empty, an alternate method of providing a password is through
 	// an environment variable
 	if password == "" {
@@ -97,20 +293,16 @@ func queryHost(host string, qryConf Nxapi) {
 	}
 
 	//fmt.Printf("password = %q\n", password)
-	cli.SetPassword(password)
-	cli.SetProtocol(qryConf.Protocol)
-	cli.SetPort(qryConf.Port)
-
-	results, err := cli.Configure([]string{
-		"show version",                       //done 2
-		"show bgp session",                   //done 2
-		"show ip route",                      //done 2
-		"show ip arp",                        //done 2
-		"show interface status",              //done 2
-		"show interface quick",               //done 2
-		"show isis adj detail",               //done 2
-		"show interface transceiver details", //
-	})
+
+	// Only the commands of the collectors which are turned on go in the batch
+	var cmds, collectors []string
+	for _, c := range collectorCommands {
+		if collectorEnabled(qryConf, c.name) {
+			cmds = append(cmds, c.cmd)
+			collectors = append(collectors, c.name)
+		}
+	}
+	results, err := runCommands(ctx, host, qryConf, password, cmds)
 
 	/* // Test data for development
 	var err error
@@ -125,61 +317,120 @@ func queryHost(host string, qryConf Nxapi) {
 	*/
 
 	if err != nil {
-		log.Println("Error in call to API for host", host, "err", err)
-		return
+		return nil, err
+	}
+	if len(results) != len(cmds) {
+		return nil, fmt.Errorf("expected %d replies, got %d", len(cmds), len(results))
 	}
 
+	// Sort the replies by collector.  A command which failed, like one for a
+	// feature which is not enabled, has no reply and is logged only once.
+	replies := make(map[string]nxapiReply)
+	for i, name := range collectors {
+		if !commandFailed(host, cmds[i], results[i].Error) {
+			replies[name] = results[i]
+		}
+	}
+	ms.Time = time.Now()
+	ms.Add(famLastScrape, float64(ms.Time.UnixNano())/1e9)
+
 	//for _, result := range results {
 	//	fmt.Fprintf(&buf,"data %#v\n\n\n", string(result.Result)) // DEBUG
 	//}
 
 	// Parse the reply into structures
-	ver_resp, err := client.NewShowVersionResultFromBytes(results[0].Result)
-	printRespErr(err, "ver", results[0].Result)
+	ver_resp, err := client.NewShowVersionResultFromBytes(replies["version"].Result)
+	printRespErr(err, "ver", replies["version"].Result)
+
+	bgp_resp, err := client.NewShowBgpSessionsResultFromBytes(replies["bgp"].Result)
+	printRespErr(err, "bgp", replies["bgp"].Result)
+
+	iprt_resp, err := client.NewShowIpRouteResultFromBytes(replies["route"].Result)
+	printRespErr(err, "iproute", replies["route"].Result)
+
+	iparp_resp, err := newArpResult(replies["arp"].Result)
+	printRespErr(err, "iparp", replies["arp"].Result)
+
+	stat_resp, err := client.NewInterfaceStatusResultFromBytes(replies["interface_status"].Result)
+	printRespErr(err, "stat", replies["interface_status"].Result)
+
+	quick_resp, err := client.NewShowInterfaceQuickResultFromBytes(replies["interface_quick"].Result)
+	printRespErr(err, "quick", replies["interface_quick"].Result)
+
+	isis_resp, err := client.NewShowIsisAdjDetailResultFromBytes(replies["isis"].Result)
+	printRespErr(err, "isis", replies["isis"].Result)
+
+	xcvr_resp, err := newTransceiverResult(replies["transceiver"].Result)
+	printRespErr(err, "xcvr", replies["transceiver"].Result)
+
+	env_resp, err := client.NewShowEnvironmentResultFromBytes(replies["environment"].Result)
+	printRespErr(err, "env", replies["environment"].Result)
+
+	sys_resp, err := client.NewShowSystemResourcesResultFromBytes(replies["resources"].Result)
+	printRespErr(err, "sysres", replies["resources"].Result)
+
+	vpc_resp, err := client.NewShowVpcResultFromBytes(replies["vpc"].Result)
+	printRespErr(err, "vpc", replies["vpc"].Result)
+
+	hsrp_resp, err := client.NewShowHsrpResultFromBytes(replies["hsrp"].Result)
+	printRespErr(err, "hsrp", replies["hsrp"].Result)
+
+	mod_resp, err := client.NewShowModuleResultFromBytes(replies["module"].Result)
+	printRespErr(err, "module", replies["module"].Result)
+
+	errs_resp, err := client.NewShowInterfaceCountersErrorsResultFromBytes(replies["interface_errors"].Result)
+	printRespErr(err, "errors", replies["interface_errors"].Result)
+
+	// The interface model parses the whole JSON-RPC object
+	intf_resp, err := client.NewInterfacesFromBytes(replies["interface"].Raw)
+	printRespErr(err, "intf", replies["interface"].Result)
 
-	bgp_resp, err := client.NewShowBgpSessionsResultFromBytes(results[1].Result)
-	printRespErr(err, "bgp", results[1].Result)
+	cdp_resp, err := client.NewShowCdpNeighborsResultFromBytes(replies["cdp"].Result)
+	printRespErr(err, "cdp", replies["cdp"].Result)
 
-	iprt_resp, err := client.NewShowIpRouteResultFromBytes(results[2].Result)
-	printRespErr(err, "iproute", results[2].Result)
+	ntp_resp, err := client.NewShowNtpPeerStatusResultFromBytes(replies["ntp"].Result)
+	printRespErr(err, "ntp", replies["ntp"].Result)
 
-	iparp_resp, err := client.NewShowIpArpResultFromBytes(results[3].Result)
-	printRespErr(err, "iparp", results[3].Result)
+	eigrp_resp, err := client.NewShowIpEigrpNeighborsVrfAllResultFromBytes(replies["eigrp"].Result)
+	printRespErr(err, "eigrp", replies["eigrp"].Result)
 
-	stat_resp, err := client.NewInterfaceStatusResultFromBytes(results[4].Result)
-	printRespErr(err, "stat", results[4].Result)
+	psec_resp, err := client.NewShowPortSecurityAddressResultFromBytes(replies["port_security"].Result)
+	printRespErr(err, "psec", replies["port_security"].Result)
 
-	quick_resp, err := client.NewShowInterfaceQuickResultFromBytes(results[5].Result)
-	printRespErr(err, "quick", results[5].Result)
+	vlan_resp, err := newVlanResult(replies["vlan"].Result)
+	printRespErr(err, "vlan", replies["vlan"].Result)
 
-	isis_resp, err := client.NewShowIsisAdjDetailResultFromBytes(results[6].Result)
-	printRespErr(err, "isis", results[6].Result)
+	ospf_resp, err := newOspfResult(replies["ospf"].Result)
+	printRespErr(err, "ospf", replies["ospf"].Result)
 
 	//
 	// Parse Version blob into metrics
 	//
 	if ver_resp != nil {
-		fmt.Fprintf(&buf, "cisco_info{biosVer=%q,sysVer=%q,boardID=%q,chassisID=%q} 1\n",
+		// Describe the switch as a resource for the backends which have them
+		if ver_resp.Body.HostName != "" {
+			ms.Resource["host.name"] = ver_resp.Body.HostName
+		}
+		ms.Resource["device.serial"] = ver_resp.Body.ProcBoardID
+		if ver_resp.Body.NxosVerStr != "" {
+			ms.Resource["os.version"] = ver_resp.Body.NxosVerStr
+		} else {
+			ms.Resource["os.version"] = ver_resp.Body.KickstartVerStr
+		}
+
+		ms.Add(famInfo, 1,
 			ver_resp.Body.BiosVerStr, ver_resp.Body.KickstartVerStr, ver_resp.Body.ProcBoardID, ver_resp.Body.ChassisID)
 
 		//const longForm = "Mon Jan 2 15:04:05 2006"
 		//t, _ := time.Parse(longForm, ver_resp.Body.RrCtime)
 
-		fmt.Fprintf(&buf, "cisco_reset_time{rr_reason=%q,rr_service=%q,rr_sysVer=%q} %d\n",
-			ver_resp.Body.RrReason, ver_resp.Body.RrService, ver_resp.Body.RrSysVer, ver_resp.Body.RrCtime.Time().Unix())
-
-		fmt.Fprintf(&buf, "cisco_uptime_seconds %v\n",
-			((ver_resp.Body.KernUptmDays*24+ver_resp.Body.KernUptmHrs)*60+
-				ver_resp.Body.KernUptmMins)*60+ver_resp.Body.KernUptmSecs)
+		ms.Add(famResetTime, float64(ver_resp.Body.RrCtime.Time().Unix()),
+			ver_resp.Body.RrReason, ver_resp.Body.RrService, ver_resp.Body.RrSysVer)
 
-		switch ver_resp.Body.MemType {
-		case "mB":
-			fmt.Fprintf(&buf, "cisco_memory %v\n", 1024*1024*ver_resp.Body.Memory)
-		case "kB":
-			fmt.Fprintf(&buf, "cisco_memory %v\n", 1024*ver_resp.Body.Memory)
-		default:
-			fmt.Fprintf(&buf, "cisco_memory{memType=%q} %v\n", ver_resp.Body.MemType, ver_resp.Body.Memory)
-		}
+		uptime := ((ver_resp.Body.KernUptmDays*24+ver_resp.Body.KernUptmHrs)*60+
+			ver_resp.Body.KernUptmMins)*60 + ver_resp.Body.KernUptmSecs
+		ms.Add(famUptime, float64(uptime))
+		ms.Start = ms.Time.Add(-time.Duration(uptime) * time.Second)
 	}
 
 	//
@@ -188,16 +439,14 @@ func queryHost(host string, qryConf Nxapi) {
 	if bgp_resp != nil {
 		bgp_slices := bgp_resp.Flat()
 		for _, b := range bgp_slices {
-			fmt.Fprintf(&buf, "cisco_bgp_lastflap_seconds{neighborID=%q,remoteAS=\"%d\",localAS=\"%d\",routerID=%q} %d\n",
-				b.NeighborID, b.RemoteAS, b.LocalAS, b.RouterID, b.LastFlap/1e9)
+			lbl := []string{b.NeighborID, fmt.Sprint(b.RemoteAS), fmt.Sprint(b.LocalAS), b.RouterID}
+			ms.Add(famBgpLastFlap, float64(b.LastFlap/1e9), lbl...)
 			state := 0
 			if b.State == "Established" {
 				state = 1
 			}
-			fmt.Fprintf(&buf, "cisco_bgp_state{neighborID=%q,remoteAS=\"%d\",localAS=\"%d\",routerID=%q} %d\n",
-				b.NeighborID, b.RemoteAS, b.LocalAS, b.RouterID, state)
-			fmt.Fprintf(&buf, "cisco_bgp_conndrop_count{neighborID=%q,remoteAS=\"%d\",localAS=\"%d\",routerID=%q} %d\n",
-				b.NeighborID, b.RemoteAS, b.LocalAS, b.RouterID, b.ConnectionsDropped)
+			ms.AddState(famBgpState, float64(state), b.State, lbl...)
+			ms.Add(famBgpConnDrop, float64(b.ConnectionsDropped), lbl...)
 		}
 	}
 
@@ -207,16 +456,12 @@ func queryHost(host string, qryConf Nxapi) {
 	if iprt_resp != nil {
 		route_slices := iprt_resp.Flat()
 		for _, r := range route_slices {
-			fmt.Fprintf(&buf, "cisco_ip_route_uptime_seconds{clientName=%q,ifName=%q,ipPrefix=%q} %d\n",
-				r.ClientName, r.IfName, r.IPPrefix, r.UpTime/1e9)
-			fmt.Fprintf(&buf, "cisco_ip_route_mcast_hops{clientName=%q,ifName=%q,ipPrefix=%q} %d\n",
-				r.ClientName, r.IfName, r.IPPrefix, r.MCastNHops)
-			fmt.Fprintf(&buf, "cisco_ip_route_ucast_hops{clientName=%q,ifName=%q,ipPrefix=%q} %d\n",
-				r.ClientName, r.IfName, r.IPPrefix, r.UCastNHops)
-			fmt.Fprintf(&buf, "cisco_ip_route_pref{clientName=%q,ifName=%q,ipPrefix=%q} %d\n",
-				r.ClientName, r.IfName, r.IPPrefix, r.Pref)
-			fmt.Fprintf(&buf, "cisco_ip_route_metric{clientName=%q,ifName=%q,ipPrefix=%q} %d\n",
-				r.ClientName, r.IfName, r.IPPrefix, r.Metric)
+			lbl := []string{r.ClientName, r.IfName, r.IPPrefix}
+			ms.Add(famRouteUptime, float64(r.UpTime/1e9), lbl...)
+			ms.Add(famRouteMcastHops, float64(r.MCastNHops), lbl...)
+			ms.Add(famRouteUcastHops, float64(r.UCastNHops), lbl...)
+			ms.Add(famRoutePref, float64(r.Pref), lbl...)
+			ms.Add(famRouteMetric, float64(r.Metric), lbl...)
 		}
 	}
 
@@ -224,11 +469,7 @@ func queryHost(host string, qryConf Nxapi) {
 	// Parse IP ARP into metrics
 	//
 	if iparp_resp != nil {
-		arp_slices := iparp_resp.Flat()
-		for _, r := range arp_slices {
-			fmt.Fprintf(&buf, "cisco_ip_arp{flags=%q,intfOut=%q,iPAddrOut=%q,mac=%q} %d\n",
-				r.Flags, r.IntfOut, r.IPAddrOut, r.MAC, r.TimeStamp/1e9)
-		}
+		collectArp(ms, iparp_resp, qryConf.ArpEntries)
 	}
 
 	//
@@ -237,8 +478,8 @@ func queryHost(host string, qryConf Nxapi) {
 	if stat_resp != nil {
 		stat_slices := stat_resp.Flat()
 		for _, r := range stat_slices {
-			fmt.Fprintf(&buf, "cisco_interface_speed_bits{interface=%q,state=%q,vlan=%q,type=%q,autoSpeed=\"%v\"} %d\n",
-				r.Interface, r.State, r.Vlan, r.Type, r.SpeedAuto, r.SpeedVal)
+			ms.Add(famIntfSpeed, float64(r.SpeedVal),
+				r.Interface, r.State, r.Vlan, r.Type, fmt.Sprint(r.SpeedAuto))
 		}
 	}
 
@@ -248,15 +489,13 @@ func queryHost(host string, qryConf Nxapi) {
 	if quick_resp != nil {
 		quick_slices := quick_resp.Flat()
 		for _, r := range quick_slices {
-			lbl := fmt.Sprintf("interface=%q,mac=%q", r.Interface, r.EthHwAddr)
-			fmt.Fprintf(&buf, "cisco_interface_info{%s,desc=%q,eth_autoneg=%q} 1\n",
-				lbl, r.Desc, r.EthAutoNeg)
+			ms.Add(famIntfInfo, 1, r.Interface, r.EthHwAddr, r.Desc, r.EthAutoNeg)
 
-			fmt.Fprintf(&buf, "cisco_interface_state{%s} %d\n", lbl, stateSwitch(r.State))
-			fmt.Fprintf(&buf, "cisco_interface_admin_state{%s} %d\n", lbl, stateSwitch(r.AdminState))
+			ms.AddState(famIntfState, float64(stateSwitch(r.State)), r.State, r.Interface, r.EthHwAddr)
+			ms.AddState(famIntfAdminState, float64(stateSwitch(r.AdminState)), r.AdminState, r.Interface, r.EthHwAddr)
 
 			for i, v := range r.EthBW {
-				fmt.Fprintf(&buf, "cisco_interface_bw_bits{%s,stream=\"%d\"} %d\n", lbl, i, v)
+				ms.Add(famIntfBW, float64(v), r.Interface, r.EthHwAddr, fmt.Sprint(i))
 			}
 
 			// Enable tracking the Vdc counters if any of them is non-zero, otherwise omit.
@@ -264,17 +503,17 @@ func queryHost(host string, qryConf Nxapi) {
 				r.VdcLvlInBCast > 0 || r.VdcLvlOutPkts > 0 || r.VdcLvlOutBytes > 0 || r.VdcLvlOutUCast > 0 ||
 				r.VdcLvlOutMCast > 0 || r.VdcLvlOutBCast > 0 {
 
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_in_pkts{%s} %d\n", lbl, r.VdcLvlInPkts)
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_in_bytes{%s} %d\n", lbl, r.VdcLvlInBytes)
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_in_ucast_pkts{%s} %d\n", lbl, r.VdcLvlInUCast)
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_in_mcast_pkts{%s} %d\n", lbl, r.VdcLvlInMCast)
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_in_bcast_pkts{%s} %d\n", lbl, r.VdcLvlInBCast)
-
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_out_pkts{%s} %d\n", lbl, r.VdcLvlOutPkts)
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_out_bytes{%s} %d\n", lbl, r.VdcLvlOutBytes)
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_out_ucast_pkts{%s} %d\n", lbl, r.VdcLvlOutUCast)
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_out_mcast_pkts{%s} %d\n", lbl, r.VdcLvlOutMCast)
-				fmt.Fprintf(&buf, "cisco_interface_vdc_lvl_out_bcast_pkts{%s} %d\n", lbl, r.VdcLvlOutBCast)
+				ms.Add(famVdcInPkts, float64(r.VdcLvlInPkts), r.Interface, r.EthHwAddr)
+				ms.Add(famVdcInBytes, float64(r.VdcLvlInBytes), r.Interface, r.EthHwAddr)
+				ms.Add(famVdcInUCast, float64(r.VdcLvlInUCast), r.Interface, r.EthHwAddr)
+				ms.Add(famVdcInMCast, float64(r.VdcLvlInMCast), r.Interface, r.EthHwAddr)
+				ms.Add(famVdcInBCast, float64(r.VdcLvlInBCast), r.Interface, r.EthHwAddr)
+
+				ms.Add(famVdcOutPkts, float64(r.VdcLvlOutPkts), r.Interface, r.EthHwAddr)
+				ms.Add(famVdcOutBytes, float64(r.VdcLvlOutBytes), r.Interface, r.EthHwAddr)
+				ms.Add(famVdcOutUCast, float64(r.VdcLvlOutUCast), r.Interface, r.EthHwAddr)
+				ms.Add(famVdcOutMCast, float64(r.VdcLvlOutMCast), r.Interface, r.EthHwAddr)
+				ms.Add(famVdcOutBCast, float64(r.VdcLvlOutBCast), r.Interface, r.EthHwAddr)
 			}
 		}
 	}
@@ -285,16 +524,108 @@ func queryHost(host string, qryConf Nxapi) {
 	if isis_resp != nil {
 		isis_slices := isis_resp.Flat()
 		for _, r := range isis_slices {
-			fmt.Fprintf(&buf, "cisco_isis_adj_transitions{intfOut=%q,iPAddrOut=%q,iP6AddrOut=%q} %d\n",
-				r.AdjIntfNameOut, r.AdjIpv4AddrOut, r.AdjIpv6AddrOut, r.AdjTransitionsOut)
+			ms.Add(famIsisAdjTransitions, float64(r.AdjTransitionsOut),
+				r.AdjIntfNameOut, r.AdjIpv4AddrOut, r.AdjIpv6AddrOut)
 		}
 	}
 
-	if config.Push == "" {
-		// Print out the result
-		fmt.Printf("metrics:\n%s", buf.String())
-	} else {
-		// Send the result to Prometheus Collector
-		UploadToCollector(strings.TrimSuffix(config.Push, "/")+"/host/"+host, buf.Bytes())
+	//
+	// Parse transceiver details into metrics
+	//
+	if xcvr_resp != nil {
+		collectTransceivers(ms, xcvr_resp)
+	}
+
+	//
+	// Parse environment into metrics
+	//
+	if env_resp != nil {
+		collectEnvironment(ms, env_resp)
+	}
+
+	//
+	// Parse system resources into metrics
+	//
+	if sys_resp != nil {
+		collectSystemResources(ms, sys_resp)
+	}
+
+	//
+	// Parse vPC status into metrics
+	//
+	if vpc_resp != nil {
+		collectVpc(ms, vpc_resp)
+	}
+
+	//
+	// Parse HSRP groups into metrics
+	//
+	if hsrp_resp != nil {
+		collectHsrp(ms, hsrp_resp)
+	}
+
+	//
+	// Parse modules into metrics
+	//
+	if mod_resp != nil {
+		collectModules(ms, mod_resp)
+	}
+
+	//
+	// Parse interface errors into metrics
+	//
+	if errs_resp != nil {
+		collectInterfaceErrors(ms, errs_resp)
+	}
+
+	//
+	// Parse interface counters into metrics
+	//
+	if intf_resp != nil {
+		collectInterfaces(ms, intf_resp, qryConf.InterfaceCounters)
+	}
+
+	//
+	// Parse CDP neighbors into metrics
+	//
+	if cdp_resp != nil {
+		collectCdp(ms, cdp_resp)
+	}
+
+	//
+	// Parse NTP peers into metrics
+	//
+	if ntp_resp != nil {
+		collectNtp(ms, ntp_resp)
 	}
+
+	//
+	// Parse EIGRP neighbors into metrics
+	//
+	if eigrp_resp != nil {
+		collectEigrp(ms, eigrp_resp)
+	}
+
+	//
+	// Parse port-security addresses into metrics
+	//
+	if psec_resp != nil {
+		collectPortSecurity(ms, psec_resp, qryConf.PortSecurityEntries)
+	}
+
+	//
+	// Parse VLANs into metrics
+	//
+	if vlan_resp != nil {
+		collectVlans(ms, vlan_resp)
+	}
+
+	//
+	// Parse OSPF neighbors into metrics
+	//
+	if ospf_resp != nil {
+		collectOspf(ms, ospf_resp)
+	}
+
+	return ms, nil
 }
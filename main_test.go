@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+func TestCheckCollectors(t *testing.T) {
+	allOff := make(map[string]bool)
+	for _, c := range collectorCommands {
+		allOff[c.name] = false
+	}
+
+	tests := []struct {
+		collectors map[string]bool
+		ok         bool
+	}{
+		{nil, true},
+		{map[string]bool{"vpc": false, "hsrp": false}, true},
+		{map[string]bool{"vpc": true}, true},
+		{map[string]bool{"vcp": false}, false},
+		{allOff, false},
+	}
+	for _, tt := range tests {
+		if err := checkCollectors(tt.collectors); (err == nil) != tt.ok {
+			t.Errorf("checkCollectors(%v) = %v", tt.collectors, err)
+		}
+	}
+}
+
+func TestCommandFailed(t *testing.T) {
+	notEnabled := &client.JSONRPCResponseError{Code: -32602, Message: "Invalid params"}
+	if commandFailed("sw1", "show vpc", nil) {
+		t.Error("a reply without an error counts as failed")
+	}
+	for i := 0; i < 2; i++ {
+		if !commandFailed("sw1", "show vpc", notEnabled) {
+			t.Error("a reply with an error does not count as failed")
+		}
+	}
+	if _, ok := failedCommands.Load("sw1 show vpc"); !ok {
+		t.Error("the failed command is not remembered")
+	}
+	commandFailed("sw1", "show vpc", nil)
+	if _, ok := failedCommands.Load("sw1 show vpc"); ok {
+		t.Error("the command is still remembered after it worked")
+	}
+}
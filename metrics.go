@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exposition formats the metrics can be written in
+const (
+	formatText        = "text"
+	formatOpenMetrics = "openmetrics"
+
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// A metric family is declared once with its help, type and label names
+type metricFamily struct {
+	Name   string
+	Help   string
+	Type   string // counter, gauge, info or stateset
+	Labels []string
+	States []string // possible states of a stateset
+}
+
+// A single sample of a family, the label values line up with the family labels
+type metricSample struct {
+	LabelValues []string
+	Value       float64
+	State       string // current state of a stateset
+}
+
+// The metrics collected from one host, grouped by family in order of first use
+type metricSet struct {
+	Time     time.Time         // when the device answered the queries
+	Start    time.Time         // when the device booted, where its counters start
+	Resource map[string]string // attributes of the device itself, for OTLP
+	families []*metricFamily
+	samples  map[*metricFamily][]metricSample
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{
+		Resource: make(map[string]string),
+		samples:  make(map[*metricFamily][]metricSample),
+	}
+}
+
+// Add a sample to a family, rows of different families may be interleaved
+func (ms *metricSet) Add(f *metricFamily, value float64, labelValues ...string) {
+	ms.add(f, metricSample{LabelValues: labelValues, Value: value})
+}
+
+// Add a sample to a stateset family.  The value is what the Prometheus text
+// format shows, OpenMetrics, remote_write and OTLP show the state itself.
+func (ms *metricSet) AddState(f *metricFamily, value float64, state string, labelValues ...string) {
+	ms.add(f, metricSample{LabelValues: labelValues, Value: value, State: state})
+}
+
+// Value of a stateset sample for the text format, 1 when in the wanted state
+func isState(s, want string) float64 {
+	if strings.EqualFold(strings.TrimSpace(s), want) {
+		return 1
+	}
+	return 0
+}
+
+type promLabel struct {
+	name, value string
+}
+
+// The labels of a sample which have a value.  An empty label value is the same
+// as no label at all in Prometheus, so every output leaves those out alike.
+func (f *metricFamily) labelsOf(s metricSample) (lbls []promLabel) {
+	for j, name := range f.Labels {
+		if s.LabelValues[j] != "" {
+			lbls = append(lbls, promLabel{name, s.LabelValues[j]})
+		}
+	}
+	return
+}
+
+// The states a stateset sample is shown with, one series each.  A state the
+// device reported which the family does not list is added at the end, so the
+// series set to 1 is never missing.
+func (f *metricFamily) statesOf(s metricSample) []string {
+	if s.State == "" {
+		return f.States
+	}
+	for _, state := range f.States {
+		if state == s.State {
+			return f.States
+		}
+	}
+	return append(f.States[:len(f.States):len(f.States)], s.State)
+}
+
+func (ms *metricSet) add(f *metricFamily, s metricSample) {
+	if _, ok := ms.samples[f]; !ok {
+		ms.families = append(ms.families, f)
+	}
+	ms.samples[f] = append(ms.samples[f], s)
+}
+
+// Render the metrics in the Prometheus text format
+func (ms *metricSet) Bytes() []byte {
+	return ms.Format(formatText)
+}
+
+// Render the metrics in the given exposition format
+func (ms *metricSet) Format(format string) []byte {
+	var buf bytes.Buffer
+	writeMetrics(&buf, format, []*metricSet{ms}, nil)
+	return buf.Bytes()
+}
+
+// Pick the exposition format from the Accept header of a scrape
+func negotiateFormat(accept string) string {
+	var textQ, omQ float64 = 0, -1
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		switch strings.TrimSpace(fields[0]) {
+		case "application/openmetrics-text":
+			if q > omQ {
+				omQ = q
+			}
+		case "text/plain", "*/*":
+			if q > textQ {
+				textQ = q
+			}
+		}
+	}
+	if omQ > 0 && omQ >= textQ {
+		return formatOpenMetrics
+	}
+	return formatText
+}
+
+// Content type to send with a given exposition format
+func contentType(format string) string {
+	if format == formatOpenMetrics {
+		return contentTypeOpenMetrics
+	}
+	return contentTypeText
+}
+
+// Write the metrics of one or more hosts in an exposition format.  Each family
+// gets its HELP and TYPE once, and when hosts are given each sample is tagged
+// with the host it came from.
+func writeMetrics(w io.Writer, format string, sets []*metricSet, hosts []string) error {
+	bw := bufio.NewWriter(w)
+	om := format == formatOpenMetrics
+
+	// Gather the families across all the sets, keeping the order of first use
+	var families []*metricFamily
+	seen := make(map[*metricFamily]bool)
+	for _, ms := range sets {
+		for _, f := range ms.families {
+			if !seen[f] {
+				seen[f] = true
+				families = append(families, f)
+			}
+		}
+	}
+
+	for _, f := range families {
+		name := sanitizeName(f.Name)
+		family, typ, suffix := name, f.Type, ""
+		if om {
+			// OpenMetrics names the family without the suffix of its samples
+			switch f.Type {
+			case "counter":
+				family, suffix = strings.TrimSuffix(name, "_total"), "_total"
+			case "info":
+				family, suffix = strings.TrimSuffix(name, "_info"), "_info"
+			}
+			bw.WriteString("# HELP " + family + " " + labelEscaper.Replace(f.Help) + "\n")
+		} else {
+			// The Prometheus text format has no info or stateset type
+			if typ == "info" || typ == "stateset" {
+				typ = "gauge"
+			}
+			bw.WriteString("# HELP " + family + " " + helpEscaper.Replace(f.Help) + "\n")
+		}
+		bw.WriteString("# TYPE " + family + " " + typ + "\n")
+
+		for i, ms := range sets {
+			for _, s := range ms.samples[f] {
+				var lbls []string
+				if hosts != nil {
+					lbls = append(lbls, "host="+quoteLabelValue(hosts[i]))
+				}
+				for _, l := range f.labelsOf(s) {
+					lbls = append(lbls, sanitizeLabelName(l.name)+"="+quoteLabelValue(l.value))
+				}
+
+				if om && f.Type == "stateset" {
+					// One sample per possible state, set to 1 for the current one
+					for _, state := range f.statesOf(s) {
+						v := "0"
+						if state == s.State {
+							v = "1"
+						}
+						writeSample(bw, family, append(lbls, family+"="+quoteLabelValue(state)), v)
+					}
+					continue
+				}
+				writeSample(bw, family+suffix, lbls, formatValue(s.Value))
+			}
+		}
+	}
+	if om {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+func writeSample(bw *bufio.Writer, name string, lbls []string, value string) {
+	bw.WriteString(name)
+	if len(lbls) > 0 {
+		bw.WriteString("{" + strings.Join(lbls, ",") + "}")
+	}
+	bw.WriteString(" " + value + "\n")
+}
+
+// Only backslash, double-quote and newline are escaped in the exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// Quote a label value, any other UTF-8 passes through as is
+func quoteLabelValue(s string) string {
+	return `"` + labelEscaper.Replace(strings.ToValidUTF8(s, "\uFFFD")) + `"`
+}
+
+// Make a metric name fit [a-zA-Z_:][a-zA-Z0-9_:]*
+func sanitizeName(s string) string {
+	return sanitize(s, true)
+}
+
+// Make a label name fit [a-zA-Z_][a-zA-Z0-9_]*
+func sanitizeLabelName(s string) string {
+	return sanitize(s, false)
+}
+
+func sanitize(s string, colon bool) string {
+	out := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r == ':' && colon:
+			return r
+		}
+		return '_'
+	}, s)
+	if len(out) > 0 && out[0] >= '0' && out[0] <= '9' {
+		out = "_" + out
+	}
+	return out
+}
+
+// Format a sample value without exponents for whole numbers
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Widen a float32 reading from the switch without the binary noise, so 0.71
+// stays 0.71 rather than becoming 0.7099999785423279
+func float32Value(v float32) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+	return f
+}
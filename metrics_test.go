@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+)
+
+// Interface descriptions as they come in from "show interface quick", with the
+// characters the exposition formats have to escape or replace
+var oddDescs = []client.ShowInterfaceQuickResultFlat{
+	{Interface: "Ethernet1/1", EthHwAddr: "0011.2233.4401", Desc: `uplink to "core"`},
+	{Interface: "Ethernet1/2", EthHwAddr: "0011.2233.4402", Desc: `C:\share\path`},
+	{Interface: "Ethernet1/3", EthHwAddr: "0011.2233.4403", Desc: "first line\nsecond line"},
+	{Interface: "Ethernet1/4", EthHwAddr: "0011.2233.4404", Desc: "rack\t12"},
+	{Interface: "Ethernet1/5", EthHwAddr: "0011.2233.4405", Desc: "caf\xe9 \xff\xfe"},
+	{Interface: "Ethernet1/6", EthHwAddr: "0011.2233.4406"},
+}
+
+func TestWriteMetricsEscaping(t *testing.T) {
+	ms := newMetricSet()
+	for _, r := range oddDescs {
+		ms.Add(famIntfInfo, 1, r.Interface, r.EthHwAddr, r.Desc, r.EthAutoNeg)
+	}
+
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{formatText, `# HELP cisco_interface_info Description and settings of the interface.
+# TYPE cisco_interface_info gauge
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/1",mac="0011.2233.4401",desc="uplink to \"core\""} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/2",mac="0011.2233.4402",desc="C:\\share\\path"} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/3",mac="0011.2233.4403",desc="first line\nsecond line"} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/4",mac="0011.2233.4404",desc="rack	12"} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/5",mac="0011.2233.4405",desc="caf� �"} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/6",mac="0011.2233.4406"} 1
+`},
+		{formatOpenMetrics, `# HELP cisco_interface Description and settings of the interface.
+# TYPE cisco_interface info
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/1",mac="0011.2233.4401",desc="uplink to \"core\""} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/2",mac="0011.2233.4402",desc="C:\\share\\path"} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/3",mac="0011.2233.4403",desc="first line\nsecond line"} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/4",mac="0011.2233.4404",desc="rack	12"} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/5",mac="0011.2233.4405",desc="caf� �"} 1
+cisco_interface_info{host="sw\"1\\",interface="Ethernet1/6",mac="0011.2233.4406"} 1
+# EOF
+`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeMetrics(&buf, tt.format, []*metricSet{ms}, []string{`sw"1\`}); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if buf.String() != tt.golden {
+			t.Errorf("%s output:\n%s\nwant:\n%s", tt.format, buf.String(), tt.golden)
+		}
+	}
+}
+
+func TestHelpEscaping(t *testing.T) {
+	f := &metricFamily{Name: "cisco_test", Type: "gauge", Help: "Path C:\\x with \"quotes\"\nand a newline."}
+	ms := newMetricSet()
+	ms.Add(f, 1)
+
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{formatText, "# HELP cisco_test Path C:\\\\x with \"quotes\"\\nand a newline.\n# TYPE cisco_test gauge\ncisco_test 1\n"},
+		{formatOpenMetrics, "# HELP cisco_test Path C:\\\\x with \\\"quotes\\\"\\nand a newline.\n# TYPE cisco_test gauge\ncisco_test 1\n# EOF\n"},
+	}
+	for _, tt := range tests {
+		if got := string(ms.Format(tt.format)); got != tt.golden {
+			t.Errorf("%s output:\n%s\nwant:\n%s", tt.format, got, tt.golden)
+		}
+	}
+}
+
+func TestStatesetFormats(t *testing.T) {
+	f := &metricFamily{Name: "cisco_test_state", Type: "stateset", Help: "State of the test, 1 when up.",
+		Labels: []string{"peer"}, States: []string{"up", "down"}}
+	ms := newMetricSet()
+	ms.AddState(f, 0, "down", "p1")
+	ms.AddState(f, 0, "Shut (Admin)", "p2")
+
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{formatText, `# HELP cisco_test_state State of the test, 1 when up.
+# TYPE cisco_test_state gauge
+cisco_test_state{peer="p1"} 0
+cisco_test_state{peer="p2"} 0
+`},
+		{formatOpenMetrics, `# HELP cisco_test_state State of the test, 1 when up.
+# TYPE cisco_test_state stateset
+cisco_test_state{peer="p1",cisco_test_state="up"} 0
+cisco_test_state{peer="p1",cisco_test_state="down"} 1
+cisco_test_state{peer="p2",cisco_test_state="up"} 0
+cisco_test_state{peer="p2",cisco_test_state="down"} 0
+cisco_test_state{peer="p2",cisco_test_state="Shut (Admin)"} 1
+# EOF
+`},
+	}
+	for _, tt := range tests {
+		if got := string(ms.Format(tt.format)); got != tt.golden {
+			t.Errorf("%s output:\n%s\nwant:\n%s", tt.format, got, tt.golden)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pschou/go-cisco-nx-api/pkg/client"
+	nxjson "github.com/pschou/go-json"
+)
+
+// The reply to one command of a batch
+type nxapiReply struct {
+	client.JSONRPCResponse
+	Raw json.RawMessage // the whole JSON-RPC object, which some models parse
+}
+
+// Run a batch of show commands on a device over JSON-RPC.  This does what
+// client.Configure does, but gives up as soon as ctx is done, so a probe which
+// timed out does not leave the query running against the device.
+func runCommands(ctx context.Context, host string, qryConf Nxapi, password string, cmds []string) ([]nxapiReply, error) {
+	payload, err := json.Marshal(client.NewJSONRPCRequest(cmds))
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s://%s:%d/ins", qryConf.Protocol, host, qryConf.Port)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json-rpc")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.SetBasicAuth(qryConf.User, password)
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+
+	// A batch of one comes back as a bare object rather than a list
+	var raw []json.RawMessage
+	if len(cmds) == 1 {
+		raw = []json.RawMessage{body}
+	} else if err = json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %s", resp.Status, err)
+	}
+
+	results := make([]nxapiReply, len(raw))
+	for i := range raw {
+		results[i].Raw = raw[i]
+		if err = json.Unmarshal(raw[i], &results[i].JSONRPCResponse); err != nil {
+			return nil, fmt.Errorf("%s: %s", resp.Status, err)
+		}
+	}
+	return results, nil
+}
+
+// Decode the reply to a command into v the way the client does, for the
+// replies which are parsed into types of our own
+func decodeResult(b []byte, v interface{}) error {
+	if len(b) == 0 {
+		return fmt.Errorf("missing result")
+	}
+	dec := nxjson.NewDecoder(bytes.NewReader(b))
+	dec.UseAutoConvert()
+	dec.UseSlice()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("parsing error: %s", err)
+	}
+	return nil
+}
+
+// A duration the way the client reads it, like "PT1H2M3S" or "01:02:03", in
+// nanoseconds.  The switch leaves some of them empty, which
+// client.ParseDuration cannot take, so those are read as zero.
+type duration uint64
+
+func (d *duration) UnmarshalText(text []byte) error {
+	if len(bytes.TrimSpace(text)) == 0 {
+		*d = 0
+		return nil
+	}
+	v, err := client.ParseDuration(string(text))
+	*d = duration(v)
+	return err
+}
+
+// A reading the switch gives as a number or a string.  Readings it does not
+// have, which it reports as "N/A" or leaves empty or out, are not valid, so
+// they can be left out rather than read as zero.
+type reading struct {
+	value float64
+	valid bool
+}
+
+func (r *reading) UnmarshalJSON(b []byte) error {
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.Trim(string(b), `"`)), 64)
+	*r = reading{v, err == nil && !math.IsNaN(v) && !math.IsInf(v, 0)}
+	return nil
+}
+
+// Commands which failed on a host, by host and command
+var failedCommands sync.Map
+
+// Tell whether a command of the batch failed.  A switch keeps failing a
+// command for a feature which is not enabled or which its release does not
+// have, so this is logged once rather than every interval, and again only
+// after the command worked in between.
+func commandFailed(host, cmd string, rpcErr *client.JSONRPCResponseError) bool {
+	key := host + " " + cmd
+	if rpcErr == nil {
+		failedCommands.Delete(key)
+		return false
+	}
+	if _, seen := failedCommands.LoadOrStore(key, true); !seen {
+		msg := rpcErr.Message
+		if detail := strings.TrimSpace(rpcErr.Data.Msg); detail != "" {
+			msg += ": " + detail
+		}
+		log.Printf("Host %s cannot run %q: %s, skipping it until it can", host, cmd, msg)
+	}
+	return true
+}
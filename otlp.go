@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OTLP aggregation temporality of the counters, they count up from boot
+const otlpCumulative = 2
+
+// Send the metrics of a host to an OpenTelemetry collector over OTLP/HTTP.
+// Each switch is a Resource, counters become monotonic cumulative Sums and
+// everything else becomes a Gauge.
+func ExportOTLP(endpoint, host string, ms *metricSet) (err error) {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/metrics") {
+		url += "/v1/metrics"
+	}
+
+	config := getConfig()
+	header := http.Header{}
+	for key, val := range config.OTLP.Headers {
+		header.Set(key, readAtFile(val))
+	}
+
+	var body []byte
+	if config.OTLP.Encoding == "json" {
+		header.Set("Content-Type", "application/json")
+		body, err = json.Marshal(otlpJSONRequest(host, ms))
+		if err != nil {
+			return
+		}
+	} else {
+		header.Set("Content-Type", "application/x-protobuf")
+		body = otlpProtoRequest(host, ms)
+	}
+
+	err = sendWithRetry("POST", url, body, header)
+	if err != nil {
+		log.Println("Error in OTLP export for host", host, "err", err)
+	} else {
+		log.Println("...pushed")
+	}
+	return
+}
+
+// A data point before it is encoded, shared by the protobuf and JSON forms
+type otlpPoint struct {
+	attrs []promLabel
+	value float64
+}
+
+// Flatten the samples of a family into data points, a stateset gets a point
+// for each of its states like it does in OpenMetrics
+func otlpPoints(f *metricFamily, ms *metricSet) (points []otlpPoint) {
+	for _, s := range ms.samples[f] {
+		// The JSON encoding has no way to carry these
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+		var attrs []promLabel
+		for _, l := range f.labelsOf(s) {
+			attrs = append(attrs, promLabel{l.name, strings.ToValidUTF8(l.value, "\uFFFD")})
+		}
+		if f.Type != "stateset" {
+			points = append(points, otlpPoint{attrs, s.Value})
+			continue
+		}
+		for _, state := range f.statesOf(s) {
+			v := 0.0
+			if state == s.State {
+				v = 1
+			}
+			points = append(points, otlpPoint{append(attrs[:len(attrs):len(attrs)], promLabel{"state", state}), v})
+		}
+	}
+	return
+}
+
+// Resource attributes of the switch, sorted for a stable encoding
+func otlpResource(host string, ms *metricSet) (attrs []promLabel) {
+	attrs = append(attrs, promLabel{"service.name", "cisco-prom"})
+	if _, ok := ms.Resource["host.name"]; !ok {
+		attrs = append(attrs, promLabel{"host.name", host})
+	}
+	for key, val := range ms.Resource {
+		attrs = append(attrs, promLabel{key, val})
+	}
+	sort.Slice(attrs, func(a, b int) bool { return attrs[a].name < attrs[b].name })
+	return
+}
+
+// Build the protobuf ExportMetricsServiceRequest:
+//
+//	ExportMetricsServiceRequest { repeated ResourceMetrics resource_metrics = 1; }
+//	ResourceMetrics  { Resource resource = 1; repeated ScopeMetrics scope_metrics = 2; }
+//	Resource         { repeated KeyValue attributes = 1; }
+//	ScopeMetrics     { InstrumentationScope scope = 1; repeated Metric metrics = 2; }
+//	Metric           { string name = 1; string description = 2; Gauge gauge = 5; Sum sum = 7; }
+//	Gauge            { repeated NumberDataPoint data_points = 1; }
+//	Sum              { repeated NumberDataPoint data_points = 1;
+//	                   AggregationTemporality aggregation_temporality = 2; bool is_monotonic = 3; }
+//	NumberDataPoint  { repeated KeyValue attributes = 7; fixed64 start_time_unix_nano = 2;
+//	                   fixed64 time_unix_nano = 3; double as_double = 4; }
+//	KeyValue         { string key = 1; AnyValue value = 2; }
+//	AnyValue         { string string_value = 1; }
+func otlpProtoRequest(host string, ms *metricSet) []byte {
+	ts := uint64(ms.Time.UnixNano())
+
+	// Counters run from when the device booted, if it said when that was
+	var start uint64
+	if !ms.Start.IsZero() {
+		start = uint64(ms.Start.UnixNano())
+	}
+
+	var resource []byte
+	for _, a := range otlpResource(host, ms) {
+		resource = appendBytesField(resource, 1, otlpProtoKeyValue(a))
+	}
+
+	var scope []byte
+	scope = appendStringField(scope, 1, "cisco-prom")
+	if version != "" {
+		scope = appendStringField(scope, 2, version)
+	}
+
+	var scopeMetrics []byte
+	scopeMetrics = appendBytesField(scopeMetrics, 1, scope)
+	for _, f := range ms.families {
+		var data []byte
+		for _, p := range otlpPoints(f, ms) {
+			var point []byte
+			if f.Type == "counter" && start != 0 {
+				point = appendFixed64Field(point, 2, start)
+			}
+			point = appendFixed64Field(point, 3, ts)
+			point = appendDoubleField(point, 4, p.value)
+			for _, a := range p.attrs {
+				point = appendBytesField(point, 7, otlpProtoKeyValue(a))
+			}
+			data = appendBytesField(data, 1, point)
+		}
+
+		var metric []byte
+		metric = appendStringField(metric, 1, sanitizeName(f.Name))
+		metric = appendStringField(metric, 2, f.Help)
+		if f.Type == "counter" {
+			data = appendVarintField(data, 2, otlpCumulative)
+			data = appendVarintField(data, 3, 1)
+			metric = appendBytesField(metric, 7, data)
+		} else {
+			metric = appendBytesField(metric, 5, data)
+		}
+		scopeMetrics = appendBytesField(scopeMetrics, 2, metric)
+	}
+
+	var resourceMetrics []byte
+	resourceMetrics = appendBytesField(resourceMetrics, 1, resource)
+	resourceMetrics = appendBytesField(resourceMetrics, 2, scopeMetrics)
+
+	return appendBytesField(nil, 1, resourceMetrics)
+}
+
+func otlpProtoKeyValue(a promLabel) []byte {
+	var kv []byte
+	kv = appendStringField(kv, 1, a.name)
+	kv = appendBytesField(kv, 2, appendStringField(nil, 1, a.value))
+	return kv
+}
+
+// The OTLP/HTTP JSON form of the same request
+type otlpJSONKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpJSONDataPoint struct {
+	Attributes        []otlpJSONKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string             `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string             `json:"timeUnixNano"`
+	AsDouble          float64            `json:"asDouble"`
+}
+
+type otlpJSONData struct {
+	DataPoints             []otlpJSONDataPoint `json:"dataPoints"`
+	AggregationTemporality int                 `json:"aggregationTemporality,omitempty"`
+	IsMonotonic            bool                `json:"isMonotonic,omitempty"`
+}
+
+type otlpJSONMetric struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Gauge       *otlpJSONData `json:"gauge,omitempty"`
+	Sum         *otlpJSONData `json:"sum,omitempty"`
+}
+
+type otlpJSONScopeMetrics struct {
+	Scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	} `json:"scope"`
+	Metrics []otlpJSONMetric `json:"metrics"`
+}
+
+type otlpJSONResourceMetrics struct {
+	Resource struct {
+		Attributes []otlpJSONKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeMetrics []otlpJSONScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpJSONExport struct {
+	ResourceMetrics []otlpJSONResourceMetrics `json:"resourceMetrics"`
+}
+
+func otlpJSONRequest(host string, ms *metricSet) *otlpJSONExport {
+	ts := strconv.FormatInt(ms.Time.UnixNano(), 10)
+	var start string
+	if !ms.Start.IsZero() {
+		start = strconv.FormatInt(ms.Start.UnixNano(), 10)
+	}
+
+	var sm otlpJSONScopeMetrics
+	sm.Scope.Name = "cisco-prom"
+	sm.Scope.Version = version
+	for _, f := range ms.families {
+		data := &otlpJSONData{}
+		for _, p := range otlpPoints(f, ms) {
+			point := otlpJSONDataPoint{
+				Attributes:   otlpJSONAttributes(p.attrs),
+				TimeUnixNano: ts,
+				AsDouble:     p.value,
+			}
+			if f.Type == "counter" {
+				point.StartTimeUnixNano = start
+			}
+			data.DataPoints = append(data.DataPoints, point)
+		}
+		metric := otlpJSONMetric{Name: sanitizeName(f.Name), Description: f.Help}
+		if f.Type == "counter" {
+			data.AggregationTemporality = otlpCumulative
+			data.IsMonotonic = true
+			metric.Sum = data
+		} else {
+			metric.Gauge = data
+		}
+		sm.Metrics = append(sm.Metrics, metric)
+	}
+
+	var rm otlpJSONResourceMetrics
+	rm.Resource.Attributes = otlpJSONAttributes(otlpResource(host, ms))
+	rm.ScopeMetrics = []otlpJSONScopeMetrics{sm}
+	return &otlpJSONExport{ResourceMetrics: []otlpJSONResourceMetrics{rm}}
+}
+
+func otlpJSONAttributes(attrs []promLabel) (out []otlpJSONKeyValue) {
+	for _, a := range attrs {
+		var kv otlpJSONKeyValue
+		kv.Key = a.name
+		kv.Value.StringValue = a.value
+		out = append(out, kv)
+	}
+	return
+}
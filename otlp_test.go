@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The parts of an ExportMetricsServiceRequest the collector sees
+type testOTLPPoint struct {
+	Attrs []promLabel
+	Start uint64
+	Time  uint64
+	Value float64
+}
+
+type testOTLPMetric struct {
+	Name, Description string
+	Sum               bool // a Sum rather than a Gauge
+	Temporality       uint64
+	Monotonic         bool
+	Points            []testOTLPPoint
+}
+
+type testOTLPRequest struct {
+	Resource []promLabel
+	Scope    string
+	Metrics  []testOTLPMetric
+}
+
+func decodeKeyValue(t *testing.T, msg []byte) (kv promLabel) {
+	for _, f := range protoFields(t, msg) {
+		switch f.num {
+		case 1:
+			kv.name = string(f.b)
+		case 2:
+			for _, vf := range protoFields(t, f.b) {
+				if vf.num == 1 {
+					kv.value = string(vf.b)
+				}
+			}
+		}
+	}
+	return
+}
+
+func decodeOTLPMetric(t *testing.T, msg []byte) (m testOTLPMetric) {
+	for _, f := range protoFields(t, msg) {
+		switch f.num {
+		case 1:
+			m.Name = string(f.b)
+		case 2:
+			m.Description = string(f.b)
+		case 5, 7:
+			m.Sum = f.num == 7
+			for _, df := range protoFields(t, f.b) {
+				switch df.num {
+				case 1:
+					var p testOTLPPoint
+					for _, pf := range protoFields(t, df.b) {
+						switch pf.num {
+						case 2:
+							p.Start = pf.v
+						case 3:
+							p.Time = pf.v
+						case 4:
+							p.Value = math.Float64frombits(pf.v)
+						case 7:
+							p.Attrs = append(p.Attrs, decodeKeyValue(t, pf.b))
+						}
+					}
+					m.Points = append(m.Points, p)
+				case 2:
+					m.Temporality = df.v
+				case 3:
+					m.Monotonic = df.v == 1
+				}
+			}
+		}
+	}
+	return
+}
+
+func decodeExportRequest(t *testing.T, msg []byte) (req testOTLPRequest) {
+	for _, f := range protoFields(t, msg) {
+		if f.num != 1 {
+			t.Fatalf("unexpected ExportMetricsServiceRequest field %d", f.num)
+		}
+		for _, rf := range protoFields(t, f.b) {
+			switch rf.num {
+			case 1:
+				for _, af := range protoFields(t, rf.b) {
+					req.Resource = append(req.Resource, decodeKeyValue(t, af.b))
+				}
+			case 2:
+				for _, sf := range protoFields(t, rf.b) {
+					switch sf.num {
+					case 1:
+						for _, nf := range protoFields(t, sf.b) {
+							if nf.num == 1 {
+								req.Scope = string(nf.b)
+							}
+						}
+					case 2:
+						req.Metrics = append(req.Metrics, decodeOTLPMetric(t, sf.b))
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// A metric set with a gauge, a counter and a stateset, from a switch which
+// booted an hour before it was queried
+func testOTLPMetricSet() *metricSet {
+	ms := newMetricSet()
+	ms.Time = time.Unix(1527099972, 0)
+	ms.Start = ms.Time.Add(-time.Hour)
+	ms.Resource["device.serial"] = "FOC21234ABC"
+	ms.Add(famUptime, 3600)
+	ms.Add(&metricFamily{Name: "cisco_test_errors", Type: "counter", Help: "Errors of the test.",
+		Labels: []string{"interface"}}, 17, "Ethernet1/1")
+	ms.AddState(&metricFamily{Name: "cisco_test_state", Type: "stateset", Help: "State of the test.",
+		States: []string{"up", "down"}}, 0, "down")
+	return ms
+}
+
+func TestOTLPProtobuf(t *testing.T) {
+	setConfig(configStruct{})
+	var got testOTLPRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" {
+			t.Errorf("path = %q, want /v1/metrics", r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+			t.Errorf("Content-Type = %q, want application/x-protobuf", ct)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		got = decodeExportRequest(t, body)
+	}))
+	defer srv.Close()
+
+	if err := ExportOTLP(srv.URL, "sw1", testOTLPMetricSet()); err != nil {
+		t.Fatal(err)
+	}
+
+	const now, boot = 1527099972e9, 1527096372e9
+	want := testOTLPRequest{
+		Resource: []promLabel{{"device.serial", "FOC21234ABC"}, {"host.name", "sw1"}, {"service.name", "cisco-prom"}},
+		Scope:    "cisco-prom",
+		Metrics: []testOTLPMetric{
+			{Name: "cisco_uptime_seconds", Description: famUptime.Help,
+				Points: []testOTLPPoint{{Time: now, Value: 3600}}},
+			{Name: "cisco_test_errors", Description: "Errors of the test.", Sum: true, Temporality: otlpCumulative, Monotonic: true,
+				Points: []testOTLPPoint{{Attrs: []promLabel{{"interface", "Ethernet1/1"}}, Start: boot, Time: now, Value: 17}}},
+			{Name: "cisco_test_state", Description: "State of the test.",
+				Points: []testOTLPPoint{{Attrs: []promLabel{{"state", "up"}}, Time: now, Value: 0},
+					{Attrs: []promLabel{{"state", "down"}}, Time: now, Value: 1}}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExportMetricsServiceRequest:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestOTLPJSON(t *testing.T) {
+	setConfig(configStruct{OTLP: OTLP{Encoding: "json"}})
+	defer setConfig(configStruct{})
+
+	var got otlpJSONExport
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decoding the request: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	if err := ExportOTLP(srv.URL+"/v1/metrics", "sw1", testOTLPMetricSet()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.ResourceMetrics) != 1 || len(got.ResourceMetrics[0].ScopeMetrics) != 1 {
+		t.Fatalf("request: %+v", got)
+	}
+	metrics := got.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 3 {
+		t.Fatalf("got %d metrics, want 3", len(metrics))
+	}
+	if g := metrics[0].Gauge; g == nil || g.DataPoints[0].StartTimeUnixNano != "" {
+		t.Errorf("uptime should be a gauge without a start time: %+v", metrics[0])
+	}
+	sum := metrics[1].Sum
+	if sum == nil || !sum.IsMonotonic || sum.AggregationTemporality != otlpCumulative {
+		t.Fatalf("counter should be a monotonic cumulative sum: %+v", metrics[1])
+	}
+	if p := sum.DataPoints[0]; p.StartTimeUnixNano != "1527096372000000000" || p.TimeUnixNano != "1527099972000000000" || p.AsDouble != 17 {
+		t.Errorf("counter point: %+v", p)
+	}
+}
+
+func TestOTLPUnknownState(t *testing.T) {
+	f := &metricFamily{Name: "cisco_test_state", Type: "stateset", States: []string{"up", "down"}}
+	ms := newMetricSet()
+	ms.AddState(f, 0, "err-pwd-dn")
+
+	want := []otlpPoint{
+		{[]promLabel{{"state", "up"}}, 0},
+		{[]promLabel{{"state", "down"}}, 0},
+		{[]promLabel{{"state", "err-pwd-dn"}}, 1},
+	}
+	if got := otlpPoints(f, ms); !reflect.DeepEqual(got, want) {
+		t.Errorf("points = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/base64"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Replace the group of a host on a Pushgateway with its latest metrics
+func UploadToPushgateway(base, job, host string, qryConf Nxapi, ms *metricSet) (err error) {
+	header := http.Header{}
+	header.Set("Content-Type", contentTypeText)
+
+	err = sendWithRetry("PUT", pushgatewayURL(base, job, host, qryConf), ms.Bytes(), header)
+	if err != nil {
+		log.Println("Error in push to pushgateway for host", host, "err", err)
+	} else {
+		log.Println("...pushed")
+	}
+	return
+}
+
+// Remove the group of a host from a Pushgateway, so it does not linger after
+// the host has been taken out of the config
+func DeleteFromPushgateway(base, job, host string, qryConf Nxapi) (err error) {
+	err = sendWithRetry("DELETE", pushgatewayURL(base, job, host, qryConf), nil, nil)
+	if err != nil {
+		log.Println("Error in delete from pushgateway for host", host, "err", err)
+	} else {
+		log.Println("Deleted host", host, "from pushgateway")
+	}
+	return
+}
+
+// Build /metrics/job/<job>/instance/<host> with the grouping labels of the
+// config block appended in sorted order
+func pushgatewayURL(base, job, host string, qryConf Nxapi) string {
+	path := strings.TrimSuffix(base, "/") + "/metrics" +
+		groupingPath("job", job) + groupingPath("instance", host)
+
+	names := make([]string, 0, len(qryConf.Grouping))
+	for name := range qryConf.Grouping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path += groupingPath(sanitizeLabelName(name), qryConf.Grouping[name])
+	}
+	return path
+}
+
+// Values which cannot sit in a path segment as is are sent base64 encoded
+func groupingPath(name, value string) string {
+	if value == "" {
+		return "/" + name + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
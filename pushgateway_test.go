@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestGroupingPath(t *testing.T) {
+	tests := []struct {
+		name, value string
+		want        string
+	}{
+		{"instance", "sw1", "/instance/sw1"},
+		{"instance", "sw1.example.com:443", "/instance/sw1.example.com:443"},
+		{"site", "dc 1", "/site/dc%201"},
+		{"site", "", "/site@base64/="},
+		{"rack", "row3/rack7", "/rack@base64/cm93My9yYWNrNw"},
+		{"rack", "a/b?", "/rack@base64/YS9iPw"},
+	}
+	for _, tt := range tests {
+		if got := groupingPath(tt.name, tt.value); got != tt.want {
+			t.Errorf("groupingPath(%q, %q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPushgatewayURL(t *testing.T) {
+	tests := []struct {
+		base, job, host string
+		grouping        map[string]string
+		want            string
+	}{
+		{"http://pg:9091", "cisco", "sw1", nil,
+			"http://pg:9091/metrics/job/cisco/instance/sw1"},
+		{"http://pg:9091/", "cisco", "sw1", nil,
+			"http://pg:9091/metrics/job/cisco/instance/sw1"},
+		{"http://pg:9091", "", "sw1", nil,
+			"http://pg:9091/metrics/job@base64/=/instance/sw1"},
+		{"http://pg:9091", "cisco", "sw1", map[string]string{"site": "dc1", "env": "", "rack": "r/7"},
+			"http://pg:9091/metrics/job/cisco/instance/sw1/env@base64/=/rack@base64/ci83/site/dc1"},
+		{"http://pg:9091", "cisco", "sw1", map[string]string{"data-center": "east"},
+			"http://pg:9091/metrics/job/cisco/instance/sw1/data_center/east"},
+	}
+	for _, tt := range tests {
+		got := pushgatewayURL(tt.base, tt.job, tt.host, Nxapi{Grouping: tt.grouping})
+		if got != tt.want {
+			t.Errorf("pushgatewayURL(%q, %q, %q, %v) =\n%s\nwant\n%s", tt.base, tt.job, tt.host, tt.grouping, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Send the metrics of a host straight to a Prometheus remote_write receiver,
+// such as Prometheus, Mimir or Thanos, as a snappy compressed WriteRequest.
+func RemoteWrite(url, host string, ms *metricSet) (err error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-protobuf")
+	header.Set("Content-Encoding", "snappy")
+	header.Set("User-Agent", "cisco-prom/"+version)
+	header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	err = sendWithRetry("POST", url, snappyEncode(encodeWriteRequest(host, ms)), header)
+	if err != nil {
+		log.Println("Error in remote write for host", host, "err", err)
+	} else {
+		log.Println("...pushed")
+	}
+	return
+}
+
+// Build the protobuf WriteRequest, one time series per sample, or per state of
+// a stateset like in OpenMetrics:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(host string, ms *metricSet) []byte {
+	ts := ms.Time.UnixNano() / 1e6
+	job := getConfig().Job
+	var req []byte
+	for _, f := range ms.families {
+		name := sanitizeName(f.Name)
+		for _, s := range ms.samples[f] {
+			lbls := []promLabel{{"__name__", name}, {"instance", host}, {"job", job}}
+			for _, l := range f.labelsOf(s) {
+				lbls = append(lbls, promLabel{sanitizeLabelName(l.name), strings.ToValidUTF8(l.value, "\uFFFD")})
+			}
+			if f.Type != "stateset" {
+				req = appendBytesField(req, 1, encodeTimeSeries(lbls, s.Value, ts))
+				continue
+			}
+			for _, state := range f.statesOf(s) {
+				v := 0.0
+				if state == s.State {
+					v = 1
+				}
+				stateLbls := append(lbls[:len(lbls):len(lbls)], promLabel{name, state})
+				req = appendBytesField(req, 1, encodeTimeSeries(stateLbls, v, ts))
+			}
+		}
+	}
+	return req
+}
+
+func encodeTimeSeries(lbls []promLabel, value float64, ts int64) []byte {
+	// Receivers expect the labels sorted by name
+	sort.Slice(lbls, func(a, b int) bool { return lbls[a].name < lbls[b].name })
+
+	var series []byte
+	for _, l := range lbls {
+		var lb []byte
+		lb = appendStringField(lb, 1, l.name)
+		lb = appendStringField(lb, 2, l.value)
+		series = appendBytesField(series, 1, lb)
+	}
+	var sample []byte
+	sample = appendDoubleField(sample, 1, value)
+	sample = appendVarintField(sample, 2, uint64(ts))
+	return appendBytesField(series, 2, sample)
+}
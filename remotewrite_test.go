@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Decode the snappy block format, the reverse of snappyEncode
+func snappyDecode(src []byte) ([]byte, error) {
+	n, l := binary.Uvarint(src)
+	if l <= 0 {
+		return nil, fmt.Errorf("bad length")
+	}
+	src = src[l:]
+	dst := make([]byte, 0, n)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0:
+			length := int(tag>>2) + 1
+			src = src[1:]
+			switch length {
+			case 61:
+				length, src = int(src[0])+1, src[1:]
+			case 62:
+				length, src = int(binary.LittleEndian.Uint16(src))+1, src[2:]
+			}
+			if length > len(src) {
+				return nil, fmt.Errorf("literal past the end")
+			}
+			dst, src = append(dst, src[:length]...), src[length:]
+		case 2:
+			length, offset := int(tag>>2)+1, int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+			if offset == 0 || offset > len(dst) {
+				return nil, fmt.Errorf("bad copy offset %d", offset)
+			}
+			for i := 0; i < length; i++ {
+				dst = append(dst, dst[len(dst)-offset])
+			}
+		default:
+			return nil, fmt.Errorf("unexpected tag %#x", tag)
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, fmt.Errorf("decoded %d bytes, want %d", len(dst), n)
+	}
+	return dst, nil
+}
+
+// A field of a protobuf message, v holds a varint or fixed64 and b the bytes
+// of a length delimited field
+type protoField struct {
+	num int
+	v   uint64
+	b   []byte
+}
+
+// Split a protobuf message into its fields
+func protoFields(t *testing.T, msg []byte) (fields []protoField) {
+	t.Helper()
+	for len(msg) > 0 {
+		key, l := binary.Uvarint(msg)
+		if l <= 0 {
+			t.Fatalf("bad field key")
+		}
+		msg = msg[l:]
+		f := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			f.v, l = binary.Uvarint(msg)
+			if l <= 0 {
+				t.Fatalf("bad varint in field %d", f.num)
+			}
+			msg = msg[l:]
+		case wireFixed64:
+			f.v, msg = binary.LittleEndian.Uint64(msg), msg[8:]
+		case wireBytes:
+			n, l := binary.Uvarint(msg)
+			if l <= 0 || uint64(len(msg)-l) < n {
+				t.Fatalf("bad length in field %d", f.num)
+			}
+			f.b, msg = msg[l:l+int(n)], msg[l+int(n):]
+		default:
+			t.Fatalf("unexpected wire type %d in field %d", key&7, f.num)
+		}
+		fields = append(fields, f)
+	}
+	return
+}
+
+// The prompb messages the receiver sees
+type testSample struct {
+	Value     float64
+	Timestamp int64
+}
+
+type testTimeSeries struct {
+	Labels  []promLabel
+	Samples []testSample
+}
+
+func decodeWriteRequest(t *testing.T, msg []byte) (series []testTimeSeries) {
+	for _, f := range protoFields(t, msg) {
+		if f.num != 1 {
+			t.Fatalf("unexpected WriteRequest field %d", f.num)
+		}
+		var ts testTimeSeries
+		for _, tf := range protoFields(t, f.b) {
+			switch tf.num {
+			case 1:
+				var l promLabel
+				for _, lf := range protoFields(t, tf.b) {
+					switch lf.num {
+					case 1:
+						l.name = string(lf.b)
+					case 2:
+						l.value = string(lf.b)
+					}
+				}
+				ts.Labels = append(ts.Labels, l)
+			case 2:
+				var s testSample
+				for _, sf := range protoFields(t, tf.b) {
+					switch sf.num {
+					case 1:
+						s.Value = math.Float64frombits(sf.v)
+					case 2:
+						s.Timestamp = int64(sf.v)
+					}
+				}
+				ts.Samples = append(ts.Samples, s)
+			}
+		}
+		series = append(series, ts)
+	}
+	return
+}
+
+func TestRemoteWrite(t *testing.T) {
+	setConfig(configStruct{Job: "cisco"})
+	famTestState := &metricFamily{Name: "cisco_test_state", Type: "stateset", Help: "State of the test.",
+		Labels: []string{"peer"}, States: []string{"up", "down"}}
+
+	ms := newMetricSet()
+	ms.Time = time.Unix(1527099972, 0)
+	ms.Add(famIntfInfo, 1, "Ethernet1/1", "0011.2233.4401", "", "")
+	ms.AddState(famTestState, 1, "up", "p1")
+	ms.AddState(famTestState, 0, "Shut (Admin)", "p2")
+
+	var got []testTimeSeries
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ce := r.Header.Get("Content-Encoding"); ce != "snappy" {
+			t.Errorf("Content-Encoding = %q, want snappy", ce)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+			t.Errorf("Content-Type = %q, want application/x-protobuf", ct)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		msg, err := snappyDecode(body)
+		if err != nil {
+			t.Errorf("snappy: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		got = decodeWriteRequest(t, msg)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := RemoteWrite(srv.URL, "sw1", ms); err != nil {
+		t.Fatal(err)
+	}
+
+	sample := func(v float64) []testSample { return []testSample{{v, 1527099972000}} }
+	want := []testTimeSeries{
+		{[]promLabel{{"__name__", "cisco_interface_info"}, {"instance", "sw1"}, {"interface", "Ethernet1/1"},
+			{"job", "cisco"}, {"mac", "0011.2233.4401"}}, sample(1)},
+		{[]promLabel{{"__name__", "cisco_test_state"}, {"cisco_test_state", "up"}, {"instance", "sw1"},
+			{"job", "cisco"}, {"peer", "p1"}}, sample(1)},
+		{[]promLabel{{"__name__", "cisco_test_state"}, {"cisco_test_state", "down"}, {"instance", "sw1"},
+			{"job", "cisco"}, {"peer", "p1"}}, sample(0)},
+		{[]promLabel{{"__name__", "cisco_test_state"}, {"cisco_test_state", "up"}, {"instance", "sw1"},
+			{"job", "cisco"}, {"peer", "p2"}}, sample(0)},
+		{[]promLabel{{"__name__", "cisco_test_state"}, {"cisco_test_state", "down"}, {"instance", "sw1"},
+			{"job", "cisco"}, {"peer", "p2"}}, sample(0)},
+		{[]promLabel{{"__name__", "cisco_test_state"}, {"cisco_test_state", "Shut (Admin)"}, {"instance", "sw1"},
+			{"job", "cisco"}, {"peer", "p2"}}, sample(1)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteRequest:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestSnappyRoundTrip(t *testing.T) {
+	src := []byte{}
+	for i := 0; i < 5000; i++ {
+		src = append(src, fmt.Sprintf("cisco_interface_info{interface=\"Ethernet1/%d\"} 1\n", i%97)...)
+	}
+	got, err := snappyDecode(snappyEncode(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(src) {
+		t.Errorf("round trip changed the %d byte input", len(src))
+	}
+}
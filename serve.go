@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Most recent metrics for each host, swapped out whole as each query finishes
+var hostMetrics = make(map[string]*metricSet)
+var hostMetricsLock sync.RWMutex
+
+// Replace the cached metrics for a host with a new set
+func setHostMetrics(host string, ms *metricSet) {
+	hostMetricsLock.Lock()
+	defer hostMetricsLock.Unlock()
+	hostMetrics[host] = ms
+}
+
+// Forget the cached metrics of a host which is no longer queried
+func dropHostMetrics(host string) {
+	hostMetricsLock.Lock()
+	defer hostMetricsLock.Unlock()
+	delete(hostMetrics, host)
+}
+
+// Start the HTTP listener so Prometheus can scrape the exporter directly
+func startListener(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", serveMetrics)
+	mux.HandleFunc("/metrics/host/", serveHostMetrics)
+	mux.HandleFunc("/probe", serveProbe)
+	log.Println("Listening for scrapes on", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// Serve the metrics of all the hosts, each tagged with a host label
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	hostMetricsLock.RLock()
+	hosts := make([]string, 0, len(hostMetrics))
+	for host := range hostMetrics {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	sets := make([]*metricSet, len(hosts))
+	for i, host := range hosts {
+		sets[i] = hostMetrics[host]
+	}
+	hostMetricsLock.RUnlock()
+
+	format := negotiateFormat(r.Header.Get("Accept"))
+	w.Header().Set("Content-Type", contentType(format))
+	writeMetrics(w, format, sets, hosts)
+}
+
+// Serve the metrics of a single host, as found in /metrics/host/<host>
+func serveHostMetrics(w http.ResponseWriter, r *http.Request) {
+	host := strings.TrimPrefix(r.URL.Path, "/metrics/host/")
+
+	hostMetricsLock.RLock()
+	ms, ok := hostMetrics[host]
+	hostMetricsLock.RUnlock()
+
+	if !ok {
+		http.Error(w, "No metrics for host "+host, http.StatusNotFound)
+		return
+	}
+	format := negotiateFormat(r.Header.Get("Accept"))
+	w.Header().Set("Content-Type", contentType(format))
+	writeMetrics(w, format, []*metricSet{ms}, nil)
+}
+
+// Query a single target on demand, as in /probe?target=<host>&module=<name>
+func serveProbe(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "Target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	module := r.URL.Query().Get("module")
+	qryConf, ok := findNxapi(getConfig(), module, target)
+	if !ok {
+		if module == "" {
+			http.Error(w, "Unknown target "+target+", name a module to probe it with", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unknown module "+module, http.StatusBadRequest)
+		}
+		return
+	}
+
+	// Stay inside the scrape timeout Prometheus gives us, leaving some slack
+	timeout := 30 * time.Second
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		if sec, err := strconv.ParseFloat(v, 64); err == nil && sec > 0 {
+			timeout = time.Duration(sec*float64(time.Second)) - 500*time.Millisecond
+			if timeout <= 0 {
+				timeout = time.Duration(sec * float64(time.Second))
+			}
+		}
+	}
+
+	// The query is dropped once the timeout passes or Prometheus hangs up
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	ms, err := collectHost(ctx, target, qryConf)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Println("Timeout in probe of host", target)
+		http.Error(w, "Probe of "+target+" timed out", http.StatusGatewayTimeout)
+		return
+	}
+	if err != nil {
+		log.Println("Error in probe of host", target, "err", err)
+		http.Error(w, "Probe of "+target+" failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	format := negotiateFormat(r.Header.Get("Accept"))
+	w.Header().Set("Content-Type", contentType(format))
+	writeMetrics(w, format, []*metricSet{ms}, nil)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindNxapi(t *testing.T) {
+	config := configStruct{Nxapi: []Nxapi{
+		{Name: "core", Host: []string{"sw1", "sw2"}, User: "core"},
+		{Name: "lab", User: "lab"},
+	}}
+	tests := []struct {
+		defaultModule  string
+		module, target string
+		want           string // user of the block found, empty for none
+	}{
+		{"", "", "sw2", "core"},
+		{"", "", "evil.example.com", ""},
+		{"lab", "", "evil.example.com", "lab"},
+		{"", "lab", "sw9", "lab"},
+		{"", "lab", "sw1", "lab"},
+		{"", "nosuch", "sw1", ""},
+		{"nosuch", "", "sw9", ""},
+	}
+	for _, tt := range tests {
+		config.DefaultModule = tt.defaultModule
+		qryConf, ok := findNxapi(config, tt.module, tt.target)
+		if ok != (tt.want != "") || qryConf.User != tt.want {
+			t.Errorf("findNxapi(%q, %q) with default_module %q = %q, %v, want %q",
+				tt.module, tt.target, tt.defaultModule, qryConf.User, ok, tt.want)
+		}
+	}
+}
+
+func TestServeHostMetrics(t *testing.T) {
+	ms := newMetricSet()
+	ms.Add(famUptime, 3600)
+	setHostMetrics("sw1", ms)
+	setHostMetrics("sw/2", ms)
+	defer dropHostMetrics("sw1")
+	defer dropHostMetrics("sw/2")
+
+	tests := []struct {
+		path, accept string
+		code         int
+		contentType  string
+		body         string
+	}{
+		{"/metrics/host/sw1", "", http.StatusOK, contentTypeText,
+			"# HELP cisco_uptime_seconds " + famUptime.Help + "\n# TYPE cisco_uptime_seconds gauge\ncisco_uptime_seconds 3600\n"},
+		{"/metrics/host/sw1", "application/openmetrics-text; version=1.0.0", http.StatusOK, contentTypeOpenMetrics,
+			"# HELP cisco_uptime_seconds " + famUptime.Help + "\n# TYPE cisco_uptime_seconds gauge\ncisco_uptime_seconds 3600\n# EOF\n"},
+		{"/metrics/host/sw/2", "", http.StatusOK, contentTypeText, "cisco_uptime_seconds 3600\n"},
+		{"/metrics/host/sw9", "", http.StatusNotFound, "", "No metrics for host sw9\n"},
+		{"/metrics/host/", "", http.StatusNotFound, "", "No metrics for host \n"},
+		{"/metrics", "", http.StatusOK, contentTypeText,
+			`cisco_uptime_seconds{host="sw/2"} 3600` + "\n" + `cisco_uptime_seconds{host="sw1"} 3600` + "\n"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		if tt.path == "/metrics" {
+			serveMetrics(w, r)
+		} else {
+			serveHostMetrics(w, r)
+		}
+
+		if w.Code != tt.code {
+			t.Errorf("%s: code %d, want %d", tt.path, w.Code, tt.code)
+		}
+		if ct := w.Header().Get("Content-Type"); tt.contentType != "" && ct != tt.contentType {
+			t.Errorf("%s: Content-Type %q, want %q", tt.path, ct, tt.contentType)
+		}
+		if body := w.Body.String(); !strings.HasSuffix(body, tt.body) {
+			t.Errorf("%s: body:\n%s\nwant it to end in:\n%s", tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	// A device which never answers, until the probe gives up on it
+	device := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client going away once the body is read
+		ioutil.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer device.Close()
+	u, _ := url.Parse(device.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+
+	setConfig(configStruct{Nxapi: []Nxapi{{Name: "lab", Host: []string{host}, Port: port, Protocol: "http",
+		User: "u", Password: "p"}}})
+	defer setConfig(configStruct{})
+
+	r := httptest.NewRequest("GET", "/probe?target="+host, nil)
+	r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "0.3")
+	w := httptest.NewRecorder()
+	start := time.Now()
+	serveProbe(w, r)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("code %d, want %d: %s", w.Code, http.StatusGatewayTimeout, w.Body.String())
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("probe took %v, the scrape timeout is 0.3s", took)
+	}
+}
+
+func TestProbeBadRequests(t *testing.T) {
+	setConfig(configStruct{Nxapi: []Nxapi{{Name: "lab", Host: []string{"sw1"}}}})
+	defer setConfig(configStruct{})
+
+	tests := []struct {
+		query string
+		body  string
+	}{
+		{"", "Target parameter is missing\n"},
+		{"target=evil.example.com", "Unknown target evil.example.com, name a module to probe it with\n"},
+		{"target=sw1&module=core", "Unknown module core\n"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		serveProbe(w, httptest.NewRequest("GET", "/probe?"+tt.query, nil))
+		if w.Code != http.StatusBadRequest || w.Body.String() != tt.body {
+			t.Errorf("%q: %d %q, want %d %q", tt.query, w.Code, w.Body.String(), http.StatusBadRequest, tt.body)
+		}
+	}
+}
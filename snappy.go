@@ -0,0 +1,74 @@
+package main
+
+import "encoding/binary"
+
+// Encode in the snappy block format, which remote_write requires.  This is a
+// plain greedy matcher; it compresses less than the reference encoder, but any
+// snappy decoder reads its output.
+func snappyEncode(src []byte) []byte {
+	dst := appendVarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		// Copies may only reach back within a 64k block
+		block := src
+		if len(block) > 1<<16 {
+			block = block[:1<<16]
+		}
+		src = src[len(block):]
+		dst = snappyEncodeBlock(dst, block)
+	}
+	return dst
+}
+
+func snappyEncodeBlock(dst, src []byte) []byte {
+	var table [1 << 14]int // last position+1 of each 4 byte hash
+	lit := 0
+	for i := 0; i+4 <= len(src); {
+		v := binary.LittleEndian.Uint32(src[i:])
+		h := (v * 0x1e35a7bd) >> 18
+		cand := table[h] - 1
+		table[h] = i + 1
+		if cand < 0 || binary.LittleEndian.Uint32(src[cand:]) != v {
+			i++
+			continue
+		}
+
+		// Extend the match as far as it goes
+		n := 4
+		for i+n < len(src) && src[cand+n] == src[i+n] {
+			n++
+		}
+		dst = snappyLiteral(dst, src[lit:i])
+		dst = snappyCopy(dst, i-cand, n)
+		i += n
+		lit = i
+	}
+	return snappyLiteral(dst, src[lit:])
+}
+
+func snappyLiteral(dst, lit []byte) []byte {
+	n := len(lit) - 1
+	switch {
+	case n < 0:
+		return dst
+	case n < 60:
+		dst = append(dst, byte(n)<<2)
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	default:
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	}
+	return append(dst, lit...)
+}
+
+// Copies with a two byte offset carry at most 64 bytes each
+func snappyCopy(dst []byte, offset, length int) []byte {
+	for length > 0 {
+		n := length
+		if n > 64 {
+			n = 64
+		}
+		dst = append(dst, byte(n-1)<<2|2, byte(offset), byte(offset>>8))
+		length -= n
+	}
+	return dst
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Write the metrics of a host to <host>.prom in the node_exporter textfile
+// collector directory.  The file is written under a temporary name first and
+// renamed into place, so node_exporter never reads half a file.
+func WriteTextfile(dir, host string, ms *metricSet) (err error) {
+	name := strings.NewReplacer("/", "_", string(os.PathSeparator), "_").Replace(host)
+
+	// The collector only reads *.prom, so the temporary file is skipped
+	var tmp *os.File
+	tmp, err = ioutil.TempFile(dir, "."+name+".prom.tmp")
+	if err != nil {
+		log.Println("Error creating textfile for host", host, "err", err)
+		return
+	}
+
+	// Every file gets a host label, node_exporter would reject the same series
+	// showing up in several files
+	err = writeMetrics(tmp, formatText, []*metricSet{ms}, []string{host})
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, name+".prom"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Println("Error writing textfile for host", host, "err", err)
+	}
+	return
+}
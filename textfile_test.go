@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTextfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "textfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ms := newMetricSet()
+	ms.Add(famUptime, 3600)
+
+	tests := []struct {
+		host, file string
+	}{
+		{"sw1", "sw1.prom"},
+		{"sw1.example.com:8443", "sw1.example.com:8443.prom"},
+		{"lab/sw2", "lab_sw2.prom"},
+	}
+	for _, tt := range tests {
+		if err := WriteTextfile(dir, tt.host, ms); err != nil {
+			t.Fatalf("%s: %v", tt.host, err)
+		}
+		path := filepath.Join(dir, tt.file)
+		dat, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.host, err)
+			continue
+		}
+		want := "# HELP cisco_uptime_seconds " + famUptime.Help + "\n# TYPE cisco_uptime_seconds gauge\n" +
+			`cisco_uptime_seconds{host="` + tt.host + `"} 3600` + "\n"
+		if string(dat) != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.file, dat, want)
+		}
+		if fi, _ := os.Stat(path); fi.Mode().Perm() != 0644 {
+			t.Errorf("%s: mode %v, want 0644", tt.file, fi.Mode().Perm())
+		}
+	}
+
+	// Writing again replaces the file and leaves no temporary files behind
+	if err := WriteTextfile(dir, "sw1", ms); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != len(tests) {
+		t.Errorf("directory holds %v, want only the %d .prom files", names, len(tests))
+	}
+}
@@ -4,36 +4,75 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-func UploadToCollector(url string, data []byte) (err error) {
-	var HTTPClient = &http.Client{
-		Timeout: time.Second * 30,
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout: 10 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout: 10 * time.Second,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
+// Collectors which have advertised that they accept OpenMetrics
+var openMetricsCollectors sync.Map
+
+// HTTP client of the queries to the devices and the pushes to a collector
+var HTTPClient = &http.Client{
+	Timeout: time.Second * 30,
+	Transport: &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout: 10 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
 		},
+	},
+}
+
+// HTTP client of the push backends, which checks the certificate of the
+// receiver, as they send it tokens and auth headers
+var pushHTTPClient = &http.Client{
+	Timeout: time.Second * 30,
+	Transport: &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout: 10 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// The client to push with, which only skips checking the certificate of the
+// receiver when insecure_skip_verify says to
+func pushClient() *http.Client {
+	if getConfig().InsecureSkipVerify {
+		return HTTPClient
 	}
+	return pushHTTPClient
+}
 
+func UploadToCollector(url string, ms *metricSet) (err error) {
 	var resp *http.Response
 	var req *http.Request
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
 	defer cancel()
 
-	req, err = http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	// Send OpenMetrics once the collector has said it can take it
+	format := formatText
+	if _, ok := openMetricsCollectors.Load(url); ok {
+		format = formatOpenMetrics
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(ms.Format(format)))
 	if err != nil {
 		log.Println("  New request error:", err)
+		return
 	}
+	req.Header.Set("Content-Type", contentType(format))
 
 	//for key, val := range Headers {
 	//	if debug {
@@ -48,6 +87,101 @@ func UploadToCollector(url string, data []byte) (err error) {
 	} else {
 		log.Println("...pushed")
 	}
+	if resp != nil {
+		resp.Body.Close()
+
+		// The collector advertises the formats it accepts in Accept-Post
+		if strings.Contains(resp.Header.Get("Accept-Post"), "application/openmetrics-text") {
+			openMetricsCollectors.Store(url, true)
+		} else {
+			openMetricsCollectors.Delete(url)
+		}
+	}
 
 	return
 }
+
+// Send a request to a push backend, retrying with exponential backoff while
+// the receiver answers with a 5xx or 429 or cannot be reached
+func sendWithRetry(method, url string, body []byte, header http.Header) (err error) {
+	backoff := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		var req *http.Request
+		var resp *http.Response
+
+		// Each attempt gets its own deadline, like a push to a collector
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			cancel()
+			return
+		}
+		for key, vals := range header {
+			req.Header[key] = vals
+		}
+
+		var msg []byte
+		resp, err = pushClient().Do(req)
+		if err == nil {
+			msg, _ = ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+		}
+		cancel()
+
+		retry := true
+		if err == nil {
+			switch {
+			case resp.StatusCode/100 == 2:
+				return nil
+			case resp.StatusCode/100 == 5, resp.StatusCode == http.StatusTooManyRequests:
+				// Honor the wait the receiver asks for, if any
+				if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+					backoff = wait
+				}
+			default:
+				retry = false
+			}
+			err = fmt.Errorf("%s %s: %s", method, url, strings.TrimSpace(resp.Status+" "+string(msg)))
+		}
+
+		if !retry || attempt >= pushRetries {
+			return
+		}
+		log.Println("  Retrying push in", backoff, "after error:", err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxPushBackoff {
+			backoff = maxPushBackoff
+		}
+	}
+}
+
+// The wait a receiver asks for in a Retry-After header, as seconds or as an
+// HTTP date, held to maxPushBackoff so one receiver cannot stall the pushes of
+// every host for hours
+func retryAfter(h string, now time.Time) (wait time.Duration, ok bool) {
+	h = strings.TrimSpace(h)
+	if sec, err := strconv.ParseInt(h, 10, 64); err == nil {
+		if max := int64(maxPushBackoff / time.Second); sec > max {
+			sec = max
+		}
+		wait = time.Duration(sec) * time.Second
+	} else if t, err := http.ParseTime(h); err == nil {
+		wait = t.Sub(now)
+	} else {
+		return 0, false
+	}
+	if wait <= 0 {
+		return 0, false
+	}
+	if wait > maxPushBackoff {
+		wait = maxPushBackoff
+	}
+	return wait, true
+}
+
+// Limits on retrying a push so one bad receiver cannot hold up the next query
+const pushRetries = 5
+const maxPushBackoff = 30 * time.Second
+
+// How long a single push may take before it is given up on
+const pushTimeout = 15 * time.Second
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2018, 5, 23, 18, 26, 12, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"soon", 0, false},
+		{"0", 0, false},
+		{"-5", 0, false},
+		{"7", 7 * time.Second, true},
+		{" 12 ", 12 * time.Second, true},
+		{"86400", maxPushBackoff, true},
+		{"99999999999999999", maxPushBackoff, true},
+		{"Wed, 23 May 2018 18:26:22 GMT", 10 * time.Second, true},
+		{"Thu, 24 May 2018 18:26:12 GMT", maxPushBackoff, true},
+		{"Wed, 23 May 2018 18:00:00 GMT", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPushClientVerifies(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	defer setConfig(configStruct{})
+
+	setConfig(configStruct{})
+	if resp, err := pushClient().Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("push to a receiver with a self-signed certificate should fail")
+	}
+	setConfig(configStruct{InsecureSkipVerify: true})
+	resp, err := pushClient().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("push with insecure_skip_verify: %v", err)
+	}
+	resp.Body.Close()
+}